@@ -0,0 +1,107 @@
+package btreeset
+
+// BTreeBag is an ordered multiset of keys
+type BTreeBag struct {
+	set   BTreeSet
+	total int
+}
+
+// Add a key, increment count if key exists
+func (tr *BTreeBag) Add(key []byte) (count int) {
+	tr.total++
+	if tr.set.root != nil {
+		if it := tr.set.root.get(key, tr.set.height); it != nil {
+			it.count++
+			return it.count
+		}
+	}
+	tr.set.Set(key)
+	return 1
+}
+
+// Remove decrement count of key, key is deleted when count reach zero
+func (tr *BTreeBag) Remove(key []byte) (count int, removed bool) {
+	if tr.set.root == nil {
+		return 0, false
+	}
+	it := tr.set.root.get(key, tr.set.height)
+	if it == nil {
+		return 0, false
+	}
+	tr.total--
+	if it.count > 1 {
+		it.count--
+		return it.count, true
+	}
+	tr.set.Delete(key)
+	return 0, true
+}
+
+// Count return multiplicity of key
+func (tr *BTreeBag) Count(key []byte) int {
+	if tr.set.root == nil {
+		return 0
+	}
+	if it := tr.set.root.get(key, tr.set.height); it != nil {
+		return it.count
+	}
+	return 0
+}
+
+// Has return true if key exists
+func (tr *BTreeBag) Has(key []byte) bool {
+	return tr.set.Has(key)
+}
+
+// Len returns total number of keys and number of distinct keys
+func (tr *BTreeBag) Len() (total, distinct int) {
+	return tr.total, tr.set.Len()
+}
+
+// Scan all keys with counts
+func (tr *BTreeBag) Scan(iter func(key []byte, count int) bool) {
+	if tr.set.root != nil {
+		tr.set.root.scanItems(func(it *item) bool {
+			return iter(it.key, it.count)
+		}, tr.set.height)
+	}
+}
+
+// Reverse all keys with counts
+func (tr *BTreeBag) Reverse(iter func(key []byte, count int) bool) {
+	if tr.set.root != nil {
+		tr.set.root.reverseItems(func(it *item) bool {
+			return iter(it.key, it.count)
+		}, tr.set.height)
+	}
+}
+
+func (n *node) scanItems(iter func(it *item) bool, height int) bool {
+	for i := 0; i < n.numItems; i++ {
+		if height > 0 && !n.children[i].scanItems(iter, height-1) {
+			return false
+		}
+		if !iter(&n.items[i]) {
+			return false
+		}
+	}
+	if height > 0 {
+		return n.children[n.numItems].scanItems(iter, height-1)
+	}
+	return true
+}
+
+func (n *node) reverseItems(iter func(it *item) bool, height int) bool {
+	if height > 0 && !n.children[n.numItems].reverseItems(iter, height-1) {
+		return false
+	}
+	for i := n.numItems - 1; i >= 0; i-- {
+		if !iter(&n.items[i]) {
+			return false
+		}
+		if height > 0 && !n.children[i].reverseItems(iter, height-1) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTreeBag(t *testing.T) {
+	var bag BTreeBag
+	assert.Equal(t, 0, bag.Count([]byte("a")))
+	_, removed := bag.Remove([]byte("a"))
+	assert.Equal(t, false, removed)
+
+	assert.Equal(t, 1, bag.Add([]byte("a")))
+	assert.Equal(t, 2, bag.Add([]byte("a")))
+	assert.Equal(t, 1, bag.Add([]byte("b")))
+	total, distinct := bag.Len()
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 2, distinct)
+
+	count, removed := bag.Remove([]byte("a"))
+	assert.Equal(t, 1, count)
+	assert.Equal(t, true, removed)
+	count, removed = bag.Remove([]byte("a"))
+	assert.Equal(t, 0, count)
+	assert.Equal(t, true, removed)
+	assert.Equal(t, false, bag.Has([]byte("a")))
+	total, distinct = bag.Len()
+	assert.Equal(t, 1, total)
+	assert.Equal(t, 1, distinct)
+}
+
+func TestBTreeBagRandom(t *testing.T) {
+	var bag BTreeBag
+	N := 2000
+	exp := make(map[string]int)
+	for i := 0; i < N*3; i++ {
+		key := fmt.Sprintf("%04d", rand.Intn(N))
+		exp[key]++
+		bag.Add([]byte(key))
+	}
+	// counts survive splits
+	for key, c := range exp {
+		assert.Equal(t, c, bag.Count([]byte(key)))
+	}
+	// remove keys one by one, counts survive merges
+	for _, i := range rand.Perm(N) {
+		key := fmt.Sprintf("%04d", i)
+		if exp[key] == 0 {
+			continue
+		}
+		bag.Remove([]byte(key))
+		exp[key]--
+		if exp[key] == 0 {
+			delete(exp, key)
+		}
+	}
+	var total int
+	var prev string
+	bag.Scan(func(key []byte, count int) bool {
+		if string(key) <= prev {
+			t.Fatal("out of order")
+		}
+		prev = string(key)
+		assert.Equal(t, exp[string(key)], count)
+		total += count
+		return true
+	})
+	n, distinct := bag.Len()
+	assert.Equal(t, total, n)
+	assert.Equal(t, len(exp), distinct)
+}
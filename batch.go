@@ -0,0 +1,142 @@
+package btreeset
+
+type batchOp struct {
+	op  byte // opSet or opDelete
+	key []byte
+}
+
+// Batch collect Set and Delete operations, applied together on Commit
+type Batch struct {
+	ops    []batchOp
+	commit func(ops []batchOp)
+}
+
+// Txn is a batch with read-your-writes view of the set
+type Txn struct {
+	Batch
+	tr *BTreeSet // copy of set with pending operations
+}
+
+func (tr *BTreeSet) apply(ops []batchOp) (changed bool) {
+	for _, op := range ops {
+		switch op.op {
+		case opSet:
+			changed = !tr.Set(op.key) || changed
+		case opDelete:
+			changed = tr.Delete(op.key) || changed
+		}
+	}
+	return
+}
+
+// Batch return new batch of operations on tree
+// BTreeSet is not safe for concurrent use, readers in other goroutines
+// need VersionedSet.Batch to see batch all at once
+func (tr *BTreeSet) Batch() *Batch {
+	return &Batch{commit: func(ops []batchOp) {
+		tr.apply(ops)
+	}}
+}
+
+// Txn begin transaction on tree
+// Reads see tree at begin with pending operations
+func (tr *BTreeSet) Txn() *Txn {
+	return &Txn{Batch: *tr.Batch(), tr: tr.Copy()}
+}
+
+// Batch return new batch of operations
+// Commit of batch create single version
+func (vs *VersionedSet) Batch() *Batch {
+	return &Batch{commit: func(ops []batchOp) {
+		vs.mu.Lock()
+		defer vs.mu.Unlock()
+		if vs.tr.apply(ops) {
+			vs.commit()
+		}
+	}}
+}
+
+// Txn begin transaction on latest version
+func (vs *VersionedSet) Txn() *Txn {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	return &Txn{Batch: *vs.Batch(), tr: vs.tr.Copy()}
+}
+
+// Set a key
+func (b *Batch) Set(key []byte) {
+	b.ops = append(b.ops, batchOp{opSet, key})
+}
+
+// Delete a key
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{opDelete, key})
+}
+
+// Len returns the number of pending operations
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Commit apply all operations, batch may be reused after commit
+func (b *Batch) Commit() {
+	if len(b.ops) > 0 {
+		b.commit(b.ops)
+	}
+	b.ops = nil
+}
+
+// Reset drop pending operations
+func (b *Batch) Reset() {
+	b.ops = nil
+}
+
+// Set a key in transaction
+func (tx *Txn) Set(key []byte) (replaced bool) {
+	tx.Batch.Set(key)
+	return tx.tr.Set(key)
+}
+
+// Delete a key in transaction
+func (tx *Txn) Delete(key []byte) (deleted bool) {
+	tx.Batch.Delete(key)
+	return tx.tr.Delete(key)
+}
+
+// Has return true if key exists, pending operations included
+func (tx *Txn) Has(key []byte) bool {
+	return tx.tr.Has(key)
+}
+
+// Len returns the number of items, pending operations included
+func (tx *Txn) Len() int {
+	return tx.tr.Len()
+}
+
+// Scan all items, pending operations included
+func (tx *Txn) Scan(iter func(key []byte) bool) {
+	tx.tr.Scan(iter)
+}
+
+// Ascend within the range [pivot, last], pending operations included
+func (tx *Txn) Ascend(pivot []byte, iter func(key []byte) bool) {
+	tx.tr.Ascend(pivot, iter)
+}
+
+// Descend within the range [pivot, first], pending operations included
+func (tx *Txn) Descend(pivot []byte, iter func(key []byte) bool) {
+	tx.tr.Descend(pivot, iter)
+}
+
+// Commit apply pending operations to the set
+// Operations are replayed, so changes made after begin are kept
+func (tx *Txn) Commit() {
+	tx.Batch.Commit()
+	tx.tr = &BTreeSet{}
+}
+
+// Rollback drop pending operations
+func (tx *Txn) Rollback() {
+	tx.Batch.Reset()
+	tx.tr = &BTreeSet{}
+}
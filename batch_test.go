@@ -0,0 +1,92 @@
+package btreeset
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	var tr BTreeSet
+	tr.Set([]byte("a"))
+	b := tr.Batch()
+	b.Set([]byte("b"))
+	b.Set([]byte("c"))
+	b.Delete([]byte("a"))
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, true, tr.Has([]byte("a")))
+	assert.Equal(t, false, tr.Has([]byte("b")))
+	b.Commit()
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, false, tr.Has([]byte("a")))
+	assert.Equal(t, 2, tr.Len())
+}
+
+func TestTxn(t *testing.T) {
+	var tr BTreeSet
+	tr.Set([]byte("a"))
+	tx := tr.Txn()
+	assert.Equal(t, false, tx.Set([]byte("b")))
+	assert.Equal(t, true, tx.Delete([]byte("a")))
+	assert.Equal(t, true, tx.Has([]byte("b")))
+	assert.Equal(t, false, tx.Has([]byte("a")))
+	var keys []string
+	tx.Ascend(nil, func(key []byte) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	assert.Equal(t, []string{"b"}, keys)
+	// tree is untouched until commit
+	assert.Equal(t, true, tr.Has([]byte("a")))
+	assert.Equal(t, false, tr.Has([]byte("b")))
+	tx.Rollback()
+	assert.Equal(t, true, tr.Has([]byte("a")))
+	assert.Equal(t, 1, tr.Len())
+
+	tx = tr.Txn()
+	tx.Set([]byte("b"))
+	tx.Commit()
+	assert.Equal(t, true, tr.Has([]byte("b")))
+	assert.Equal(t, 2, tr.Len())
+}
+
+func TestVersionedSetBatch(t *testing.T) {
+	var vs VersionedSet
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			// readers see all keys of batch or none
+			snap := vs.Latest()
+			assert.Equal(t, 0, snap.Len()%100)
+			snap.Close()
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		b := vs.Batch()
+		for j := 0; j < 100; j++ {
+			b.Set([]byte(fmt.Sprintf("%d:%d", i, j)))
+		}
+		b.Commit()
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, uint64(50), vs.Version())
+	assert.Equal(t, 5000, vs.Len())
+
+	tx := vs.Txn()
+	tx.Delete([]byte("0:0"))
+	assert.Equal(t, true, vs.Has([]byte("0:0")))
+	tx.Commit()
+	assert.Equal(t, false, vs.Has([]byte("0:0")))
+	assert.Equal(t, uint64(51), vs.Version())
+}
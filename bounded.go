@@ -0,0 +1,170 @@
+package btreeset
+
+import (
+	"container/list"
+	"encoding/binary"
+)
+
+// EvictPolicy select key evicted from full BoundedSet
+type EvictPolicy int
+
+const (
+	// EvictSmallest evict first key
+	EvictSmallest EvictPolicy = iota
+	// EvictLargest evict last key
+	EvictLargest
+	// EvictLRU evict least recently used key
+	EvictLRU
+	// EvictLFU evict least frequently used key, least recently used of them
+	EvictLFU
+)
+
+// BoundedSet is a set with at most Capacity keys
+// Set and Has are uses of key for LRU and LFU
+// Zero value is unlimited set evicting smallest key
+type BoundedSet struct {
+	tr BTreeSet
+	// Capacity is max number of keys, 0 - unlimited
+	Capacity int
+	// OnEvict is called with evicted key
+	OnEvict func(key []byte)
+
+	// policy is fixed, LRU and LFU track uses of keys since creation
+	policy EvictPolicy
+
+	lru  *list.List
+	elem map[string]*list.Element
+	freq map[string]lfuEntry
+	lfu  BTreeSet // freq | tick | key
+	tick uint64
+}
+
+// NewBoundedSet return set with capacity evicting by policy
+func NewBoundedSet(capacity int, policy EvictPolicy) *BoundedSet {
+	return &BoundedSet{Capacity: capacity, policy: policy}
+}
+
+// Policy return eviction policy
+func (s *BoundedSet) Policy() EvictPolicy {
+	return s.policy
+}
+
+type lfuEntry struct {
+	freq, tick uint64
+}
+
+func lfuKey(e lfuEntry, key []byte) []byte {
+	p := make([]byte, 16, 16+len(key))
+	binary.BigEndian.PutUint64(p, e.freq)
+	binary.BigEndian.PutUint64(p[8:], e.tick)
+	return append(p, key...)
+}
+
+// touch record use of existing or new key
+func (s *BoundedSet) touch(key []byte) {
+	switch s.policy {
+	case EvictLRU:
+		if s.lru == nil {
+			s.lru, s.elem = list.New(), make(map[string]*list.Element)
+		}
+		if e, ok := s.elem[string(key)]; ok {
+			s.lru.MoveToFront(e)
+			return
+		}
+		s.elem[string(key)] = s.lru.PushFront(key)
+	case EvictLFU:
+		if s.freq == nil {
+			s.freq = make(map[string]lfuEntry)
+		}
+		e, ok := s.freq[string(key)]
+		if ok {
+			s.lfu.Delete(lfuKey(e, key))
+		}
+		s.tick++
+		e = lfuEntry{freq: e.freq + 1, tick: s.tick}
+		s.freq[string(key)] = e
+		s.lfu.Set(lfuKey(e, key))
+	}
+}
+
+func (s *BoundedSet) forget(key []byte) {
+	switch s.policy {
+	case EvictLRU:
+		if e, ok := s.elem[string(key)]; ok {
+			s.lru.Remove(e)
+			delete(s.elem, string(key))
+		}
+	case EvictLFU:
+		if e, ok := s.freq[string(key)]; ok {
+			s.lfu.Delete(lfuKey(e, key))
+			delete(s.freq, string(key))
+		}
+	}
+}
+
+// victim return key to evict
+func (s *BoundedSet) victim() []byte {
+	switch s.policy {
+	case EvictLargest:
+		return s.tr.Last()
+	case EvictLRU:
+		return s.lru.Back().Value.([]byte)
+	case EvictLFU:
+		return s.lfu.First()[16:]
+	}
+	return s.tr.First()
+}
+
+// Set a key, evicting other key if set is full
+func (s *BoundedSet) Set(key []byte) (replaced bool) {
+	if s.tr.Has(key) {
+		s.touch(key)
+		return true
+	}
+	if s.Capacity > 0 {
+		for s.tr.Len() >= s.Capacity {
+			victim := s.victim()
+			s.forget(victim)
+			s.tr.Delete(victim)
+			if s.OnEvict != nil {
+				s.OnEvict(victim)
+			}
+		}
+	}
+	s.tr.Set(key)
+	s.touch(key)
+	return false
+}
+
+// Has return true if key exists
+func (s *BoundedSet) Has(key []byte) bool {
+	if !s.tr.Has(key) {
+		return false
+	}
+	s.touch(key)
+	return true
+}
+
+// Delete a key
+func (s *BoundedSet) Delete(key []byte) (deleted bool) {
+	if !s.tr.Delete(key) {
+		return false
+	}
+	s.forget(key)
+	return true
+}
+
+// Len returns the number of keys
+func (s *BoundedSet) Len() int {
+	return s.tr.Len()
+}
+
+// Ascend the set within the range [pivot, last], it's not a use of keys
+func (s *BoundedSet) Ascend(pivot []byte, iter func(key []byte) bool) {
+	s.tr.Ascend(pivot, iter)
+}
+
+// Descend the set within the range [pivot, first], it's not a use of keys
+func (s *BoundedSet) Descend(pivot []byte, iter func(key []byte) bool) {
+	s.tr.Descend(pivot, iter)
+}
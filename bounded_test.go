@@ -0,0 +1,44 @@
+package btreeset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedSet(t *testing.T) {
+	for _, c := range []struct {
+		policy  EvictPolicy
+		evicted []string
+	}{
+		{EvictSmallest, []string{"a", "b"}},
+		{EvictLargest, []string{"c", "d"}},
+		{EvictLRU, []string{"c", "b"}},
+		{EvictLFU, []string{"c", "d"}},
+	} {
+		var evicted []string
+		s := NewBoundedSet(3, c.policy)
+		s.OnEvict = func(key []byte) {
+			evicted = append(evicted, string(key))
+		}
+		assert.Equal(t, c.policy, s.Policy())
+		s.Set([]byte("c"))
+		s.Set([]byte("b"))
+		s.Set([]byte("a"))
+		// a used 3 times, b twice, c once
+		s.Has([]byte("a"))
+		s.Has([]byte("b"))
+		assert.Equal(t, true, s.Set([]byte("a")))
+		s.Set([]byte("d"))
+		s.Set([]byte("e"))
+		assert.Equal(t, c.evicted, evicted, c.policy)
+		assert.Equal(t, 3, s.Len())
+		for _, key := range evicted {
+			assert.Equal(t, false, s.Has([]byte(key)))
+		}
+		assert.Equal(t, true, s.Delete([]byte("e")))
+		assert.Equal(t, false, s.Delete([]byte("e")))
+		s.Set([]byte("f"))
+		assert.Equal(t, 2, len(evicted))
+	}
+}
@@ -10,21 +10,65 @@ const maxItems = 255
 const minItems = maxItems * 40 / 100
 
 type item struct {
-	key []byte
+	key   []byte
+	count int // multiplicity, used by BTreeBag
 }
 
 type node struct {
 	numItems int
 	items    [maxItems]item
 	children [maxItems + 1]*node
+	aug      []byte // subtree summary, maintained by augmenter
+	cow      *cow   // owner, node is shared with copies of tree if not equal to tree cow
+}
+
+// cow is copy-on-write token of tree
+type cow struct {
+	_ byte // non zero size, so every token has unique address
+}
+
+// mut return node which tree with token c may modify
+func (n *node) mut(c *cow) *node {
+	if n.cow == c {
+		return n
+	}
+	clone := *n
+	clone.cow = c
+	return &clone
+}
+
+// augmenter recalculate node summary from items and children summaries
+type augmenter func(n *node, height int)
+
+func (a augmenter) fix(n *node, height int) {
+	if a != nil {
+		a(n, height)
+	}
 }
 
 // BTreeSet is an ordered set of keys
 type BTreeSet struct {
 	//sync.RWMutex
-	height int
-	root   *node
-	length int
+	height   int
+	root     *node
+	length   int
+	augment  augmenter
+	hashed   bool // augment is merkleHash
+	cow      *cow
+	watchers []*Watcher
+	nodes    int
+	keyBytes int
+	memLimit int
+}
+
+// Copy return copy of tree in O(1), nodes are shared
+// and cloned on write by tree which modify them. Watchers are not copied
+func (tr *BTreeSet) Copy() *BTreeSet {
+	tr.cow = new(cow)
+	tr2 := *tr
+	tr2.cow = new(cow)
+	tr2.watchers = nil
+	return &tr2
 }
 
 func (n *node) find(key []byte) (index int, found bool) {
@@ -64,33 +108,54 @@ func (n *node) findLast(key []byte) (index int, found bool) {
 func (tr *BTreeSet) Set(key []byte) (replaced bool) {
 	//tr.Lock()
 	//defer tr.Unlock()
+	replaced = tr.set(key)
+	if tr.watchers != nil {
+		tr.notify(Event{Op: OpSet, Key: key, Replaced: replaced})
+	}
+	return
+}
+
+func (tr *BTreeSet) set(key []byte) (replaced bool) {
 	if tr.root == nil {
-		tr.root = new(node)
-		tr.root.items[0] = item{key}
+		tr.root = &node{cow: tr.cow}
+		tr.root.items[0] = item{key: key, count: 1}
 		tr.root.numItems = 1
 		tr.length = 1
+		tr.nodes = 1
+		tr.keyBytes = len(key)
+		tr.augment.fix(tr.root, 0)
 		return
 	}
-	replaced = tr.root.set(key, tr.height)
+	if tr.cow != nil && tr.root.has(key, tr.height) {
+		// don't clone shared nodes if nothing changed
+		return true
+	}
+	tr.root = tr.root.mut(tr.cow)
+	replaced = tr.root.set(key, tr.height, tr)
 	if replaced {
 		return
 	}
 	if tr.root.numItems == maxItems {
 		n := tr.root
-		right, median := n.split(tr.height)
-		tr.root = new(node)
+		right, median := n.split(tr.height, tr.cow)
+		tr.augment.fix(n, tr.height)
+		tr.augment.fix(right, tr.height)
+		tr.root = &node{cow: tr.cow}
 		tr.root.children[0] = n
 		tr.root.items[0] = median
 		tr.root.children[1] = right
 		tr.root.numItems = 1
 		tr.height++
+		tr.nodes += 2
+		tr.augment.fix(tr.root, tr.height)
 	}
 	tr.length++
+	tr.keyBytes += len(key)
 	return
 }
 
-func (n *node) split(height int) (right *node, median item) {
-	right = new(node)
+func (n *node) split(height int, c *cow) (right *node, median item) {
+	right = &node{cow: c}
 	median = n.items[maxItems/2]
 	copy(right.items[:maxItems/2], n.items[maxItems/2+1:])
 	if height > 0 {
@@ -109,7 +174,8 @@ func (n *node) split(height int) (right *node, median item) {
 	return
 }
 
-func (n *node) set(key []byte, height int) (replaced bool) {
+func (n *node) set(key []byte, height int, tr *BTreeSet) (replaced bool) {
+	aug := tr.augment
 	i, found := n.find(key)
 	if found {
 		return true
@@ -118,25 +184,45 @@ func (n *node) set(key []byte, height int) (replaced bool) {
 		for j := n.numItems; j > i; j-- {
 			n.items[j] = n.items[j-1]
 		}
-		n.items[i] = item{key}
+		n.items[i] = item{key: key, count: 1}
 		n.numItems++
+		aug.fix(n, height)
 		return false
 	}
-	replaced = n.children[i].set(key, height-1)
+	n.children[i] = n.children[i].mut(tr.cow)
+	replaced = n.children[i].set(key, height-1, tr)
 	if replaced {
 		return
 	}
 	if n.children[i].numItems == maxItems {
-		right, median := n.children[i].split(height - 1)
+		right, median := n.children[i].split(height-1, tr.cow)
+		tr.nodes++
+		aug.fix(n.children[i], height-1)
+		aug.fix(right, height-1)
 		copy(n.children[i+1:], n.children[i:])
 		copy(n.items[i+1:], n.items[i:])
 		n.items[i] = median
 		n.children[i+1] = right
 		n.numItems++
 	}
+	aug.fix(n, height)
 	return
 }
 
+func (n *node) get(key []byte, height int) *item {
+	for {
+		i, found := n.find(key)
+		if found {
+			return &n.items[i]
+		}
+		if height == 0 {
+			return nil
+		}
+		n = n.children[i]
+		height--
+	}
+}
+
 // Scan all items in tree
 func (tr *BTreeSet) Scan(iter func(key []byte) bool) {
 	if tr.root != nil {
@@ -201,10 +287,22 @@ func (tr *BTreeSet) Len() int {
 
 // Delete a key
 func (tr *BTreeSet) Delete(key []byte) (deleted bool) {
+	deleted = tr.delete(key)
+	if deleted && tr.watchers != nil {
+		tr.notify(Event{Op: OpDelete, Key: key})
+	}
+	return
+}
+
+func (tr *BTreeSet) delete(key []byte) (deleted bool) {
 	if tr.root == nil {
 		return
 	}
-	_, deleted = tr.root.delete(false, key, tr.height)
+	if tr.cow != nil && !tr.root.has(key, tr.height) {
+		return
+	}
+	tr.root = tr.root.mut(tr.cow)
+	prev, deleted := tr.root.delete(false, key, tr.height, tr)
 	if !deleted {
 		return
 	}
@@ -212,16 +310,20 @@ func (tr *BTreeSet) Delete(key []byte) (deleted bool) {
 	if tr.root.numItems == 0 {
 		tr.root = tr.root.children[0]
 		tr.height--
+		tr.nodes--
 	}
 	tr.length--
+	tr.keyBytes -= len(prev.key)
 	if tr.length == 0 {
 		tr.root = nil
 		tr.height = 0
+		tr.nodes = 0
 	}
 	return
 }
 
-func (n *node) delete(max bool, key []byte, height int) (prev item, deleted bool) {
+func (n *node) delete(max bool, key []byte, height int, tr *BTreeSet) (prev item, deleted bool) {
+	aug := tr.augment
 	i, found := 0, false
 	if max {
 		i, found = n.numItems-1, true
@@ -236,23 +338,27 @@ func (n *node) delete(max bool, key []byte, height int) (prev item, deleted bool
 			n.items[n.numItems-1] = item{}
 			n.children[n.numItems] = nil
 			n.numItems--
+			aug.fix(n, height)
 			return prev, true
 		}
 		return item{}, false
 	}
 
+	if found && max {
+		i++
+	}
+	n.children[i] = n.children[i].mut(tr.cow)
 	if found {
 		if max {
-			i++
-			prev, deleted = n.children[i].delete(true, nil, height-1)
+			prev, deleted = n.children[i].delete(true, nil, height-1, tr)
 		} else {
 			prev = n.items[i]
-			maxItem, _ := n.children[i].delete(true, nil, height-1)
+			maxItem, _ := n.children[i].delete(true, nil, height-1, tr)
 			n.items[i] = maxItem
 			deleted = true
 		}
 	} else {
-		prev, deleted = n.children[i].delete(max, key, height-1)
+		prev, deleted = n.children[i].delete(max, key, height-1, tr)
 	}
 	if !deleted {
 		return
@@ -261,6 +367,8 @@ func (n *node) delete(max bool, key []byte, height int) (prev item, deleted bool
 		if i == n.numItems {
 			i--
 		}
+		n.children[i] = n.children[i].mut(tr.cow)
+		n.children[i+1] = n.children[i+1].mut(tr.cow)
 		if n.children[i].numItems+n.children[i+1].numItems+1 < maxItems {
 			// merge left + item + right
 			n.children[i].items[n.children[i].numItems] = n.items[i]
@@ -273,9 +381,10 @@ func (n *node) delete(max bool, key []byte, height int) (prev item, deleted bool
 			n.children[i].numItems += n.children[i+1].numItems + 1
 			copy(n.items[i:], n.items[i+1:n.numItems])
 			copy(n.children[i+1:], n.children[i+2:n.numItems+1])
-			n.items[n.numItems] = item{}
-			n.children[n.numItems+1] = nil
+			n.items[n.numItems-1] = item{}
+			n.children[n.numItems] = nil
 			n.numItems--
+			tr.nodes--
 		} else if n.children[i].numItems > n.children[i+1].numItems {
 			// move left -> right
 			copy(n.children[i+1].items[1:],
@@ -307,13 +416,20 @@ func (n *node) delete(max bool, key []byte, height int) (prev item, deleted bool
 			n.items[i] = n.children[i+1].items[0]
 			copy(n.children[i+1].items[:],
 				n.children[i+1].items[1:n.children[i+1].numItems])
+			n.children[i+1].items[n.children[i+1].numItems-1] = item{}
 			if height > 1 {
 				copy(n.children[i+1].children[:],
 					n.children[i+1].children[1:n.children[i+1].numItems+1])
+				n.children[i+1].children[n.children[i+1].numItems] = nil
 			}
 			n.children[i+1].numItems--
 		}
+		aug.fix(n.children[i], height-1)
+		if i < n.numItems {
+			aug.fix(n.children[i+1], height-1)
+		}
 	}
+	aug.fix(n, height)
 	return
 }
 
@@ -479,16 +595,15 @@ func KeyToBinary(v interface{}) ([]byte, error) {
 
 // ValToBinary return value in bytes
 func ValToBinary(v interface{}) ([]byte, error) {
-	var err error
+	return ValToBinaryCodec(v, Gob)
+}
+
+// ValToBinaryCodec return value in bytes, encoded with codec
+func ValToBinaryCodec(v interface{}, codec Codec) ([]byte, error) {
 	switch v.(type) {
 	case []byte:
 		return v.([]byte), nil
 	default:
-		buf := new(bytes.Buffer)
-		err = gob.NewEncoder(buf).Encode(v)
-		if err != nil {
-			return nil, err
-		}
-		return buf.Bytes(), err
+		return codec.Marshal(v)
 	}
 }
@@ -0,0 +1,113 @@
+package btreeset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrUnsupportedType returned by BinaryCodec for non primitive values
+var ErrUnsupportedType = errors.New("btreeset: unsupported type")
+
+// Codec marshal and unmarshal values
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSON codec
+	JSON Codec = jsonCodec{}
+	// Gob codec, default for ValToBinary, every value is self-describing
+	Gob Codec = &gobCodec{}
+	// Binary codec for bool, numbers, string and []byte
+	Binary Codec = binaryCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// gobCodec reuse buffers between calls, but not encoders: gob encoder
+// send type description only with first value of stream, so values
+// of reused encoder can't be decoded alone. Every value is encoded
+// with own encoder and carry its type description
+type gobCodec struct {
+	pool sync.Pool
+}
+
+func (c *gobCodec) Marshal(v interface{}) ([]byte, error) {
+	buf, _ := c.pool.Get().(*bytes.Buffer)
+	if buf == nil {
+		buf = new(bytes.Buffer)
+	}
+	buf.Reset()
+	defer c.pool.Put(buf)
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+func (c *gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case int:
+		p := make([]byte, 8)
+		binary.BigEndian.PutUint64(p, uint64(v))
+		return p, nil
+	case uint:
+		p := make([]byte, 8)
+		binary.BigEndian.PutUint64(p, uint64(v))
+		return p, nil
+	case bool, float32, float64, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		buf := new(bytes.Buffer)
+		err := binary.Write(buf, binary.BigEndian, v)
+		return buf.Bytes(), err
+	}
+	return nil, ErrUnsupportedType
+}
+
+func (binaryCodec) Unmarshal(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *[]byte:
+		*v = append((*v)[:0], data...)
+		return nil
+	case *string:
+		*v = string(data)
+		return nil
+	case *int:
+		if len(data) != 8 {
+			return ErrUnsupportedType
+		}
+		*v = int(binary.BigEndian.Uint64(data))
+		return nil
+	case *uint:
+		if len(data) != 8 {
+			return ErrUnsupportedType
+		}
+		*v = uint(binary.BigEndian.Uint64(data))
+		return nil
+	case *bool, *float32, *float64, *int8, *int16, *int32, *int64, *uint8, *uint16, *uint32, *uint64:
+		return binary.Read(bytes.NewReader(data), binary.BigEndian, v)
+	}
+	return ErrUnsupportedType
+}
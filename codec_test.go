@@ -0,0 +1,50 @@
+package btreeset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codecVal struct {
+	Name string
+	Age  int
+}
+
+func TestCodec(t *testing.T) {
+	for _, c := range []Codec{JSON, Gob} {
+		in := codecVal{"bob", 42}
+		b, err := ValToBinaryCodec(in, c)
+		assert.NoError(t, err)
+		var out codecVal
+		assert.NoError(t, c.Unmarshal(b, &out))
+		assert.Equal(t, in, out)
+	}
+
+	b, err := ValToBinaryCodec(int64(-7), Binary)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(b))
+	var i64 int64
+	assert.NoError(t, Binary.Unmarshal(b, &i64))
+	assert.Equal(t, int64(-7), i64)
+
+	b, err = Binary.Marshal(3.5)
+	assert.NoError(t, err)
+	var f float64
+	assert.NoError(t, Binary.Unmarshal(b, &f))
+	assert.Equal(t, 3.5, f)
+
+	b, err = Binary.Marshal(42)
+	assert.NoError(t, err)
+	var i int
+	assert.NoError(t, Binary.Unmarshal(b, &i))
+	assert.Equal(t, 42, i)
+
+	_, err = Binary.Marshal(codecVal{})
+	assert.Equal(t, ErrUnsupportedType, err)
+
+	// []byte is passed as is
+	b, err = ValToBinary([]byte("raw"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("raw"), b)
+}
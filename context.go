@@ -0,0 +1,78 @@
+package btreeset
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStopped returned by context-aware iteration stopped by iter
+var ErrStopped = errors.New("btreeset: iteration stopped")
+
+// withContext wrap iter to stop when ctx is done
+// err return ctx.Err() if walk was stopped by ctx, ErrStopped if by iter
+func withContext(ctx context.Context, iter func(key []byte) bool) (wrapped func(key []byte) bool, err func() error) {
+	done := ctx.Done()
+	var stopped error
+	wrapped = func(key []byte) bool {
+		if done != nil {
+			select {
+			case <-done:
+				stopped = ctx.Err()
+				return false
+			default:
+			}
+		}
+		if !iter(key) {
+			stopped = ErrStopped
+			return false
+		}
+		return true
+	}
+	return wrapped, func() error { return stopped }
+}
+
+// ScanContext scan all items until iter return false or ctx is done
+// Return ctx.Err() if walk was stopped by ctx, ErrStopped if by iter
+func (tr *BTreeSet) ScanContext(ctx context.Context, iter func(key []byte) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	iter, err := withContext(ctx, iter)
+	tr.Scan(iter)
+	return err()
+}
+
+// ReverseContext reverse all items until iter return false or ctx is done
+// Return ctx.Err() if walk was stopped by ctx, ErrStopped if by iter
+func (tr *BTreeSet) ReverseContext(ctx context.Context, iter func(key []byte) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	iter, err := withContext(ctx, iter)
+	tr.Reverse(iter)
+	return err()
+}
+
+// AscendContext ascend within the range [pivot, last] until iter return
+// false or ctx is done. Return ctx.Err() if walk was stopped by ctx,
+// ErrStopped if by iter
+func (tr *BTreeSet) AscendContext(ctx context.Context, pivot []byte, iter func(key []byte) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	iter, err := withContext(ctx, iter)
+	tr.Ascend(pivot, iter)
+	return err()
+}
+
+// DescendContext descend within the range [pivot, first] until iter return
+// false or ctx is done. Return ctx.Err() if walk was stopped by ctx,
+// ErrStopped if by iter
+func (tr *BTreeSet) DescendContext(ctx context.Context, pivot []byte, iter func(key []byte) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	iter, err := withContext(ctx, iter)
+	tr.Descend(pivot, iter)
+	return err()
+}
@@ -0,0 +1,54 @@
+package btreeset
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanContext(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 1000; i++ {
+		tr.Set([]byte(fmt.Sprintf("%04d", i)))
+	}
+	n := 0
+	count := func(key []byte) bool {
+		n++
+		return true
+	}
+	assert.NoError(t, tr.ScanContext(context.Background(), count))
+	assert.Equal(t, 1000, n)
+	assert.Equal(t, ErrStopped, tr.ScanContext(context.Background(), func(key []byte) bool {
+		return false
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n = 0
+	assert.NoError(t, tr.AscendContext(ctx, []byte("0500"), count))
+	assert.Equal(t, 500, n)
+
+	// stopped by iter is distinguishable from complete walk
+	n = 0
+	assert.Equal(t, ErrStopped, tr.DescendContext(ctx, []byte("0500"), func(key []byte) bool {
+		n++
+		return n < 10
+	}))
+	assert.Equal(t, 10, n)
+
+	n = 0
+	err := tr.ScanContext(ctx, func(key []byte) bool {
+		n++
+		if n == 100 {
+			cancel()
+		}
+		return true
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 100, n)
+
+	n = 0
+	assert.Equal(t, context.Canceled, tr.ReverseContext(ctx, count))
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,129 @@
+package btreeset
+
+// cursor walk tree in order without callbacks, so several trees
+// may be walked together. Tree must not change while walking
+type cursor struct {
+	stack []cursorFrame
+}
+
+// cursorFrame point to current item i of node on path
+type cursorFrame struct {
+	n      *node
+	height int
+	i      int
+}
+
+// seek position cursor at first key >= pivot
+func (c *cursor) seek(tr *BTreeSet, pivot []byte) {
+	c.stack = c.stack[:0]
+	n := tr.root
+	for height := tr.height; n != nil; height-- {
+		i, found := n.find(pivot)
+		c.stack = append(c.stack, cursorFrame{n, height, i})
+		if found || height == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	c.skipEnded()
+}
+
+// seekReverse position cursor at last key <= pivot
+func (c *cursor) seekReverse(tr *BTreeSet, pivot []byte) {
+	c.stack = c.stack[:0]
+	n := tr.root
+	for height := tr.height; n != nil; height-- {
+		i, found := n.find(pivot)
+		if found {
+			c.stack = append(c.stack, cursorFrame{n, height, i})
+			break
+		}
+		// keys of child i are before item i, item i-1 is next after it
+		c.stack = append(c.stack, cursorFrame{n, height, i - 1})
+		if height == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	c.skipEndedReverse()
+}
+
+// last position cursor at last key
+func (c *cursor) last(tr *BTreeSet) {
+	c.stack = c.stack[:0]
+	if tr.root != nil {
+		c.pushRight(tr.root, tr.height)
+	}
+}
+
+func (c *cursor) pushLeft(n *node, height int) {
+	for {
+		c.stack = append(c.stack, cursorFrame{n, height, 0})
+		if height == 0 {
+			return
+		}
+		n = n.children[0]
+		height--
+	}
+}
+
+func (c *cursor) pushRight(n *node, height int) {
+	for {
+		c.stack = append(c.stack, cursorFrame{n, height, n.numItems - 1})
+		if height == 0 {
+			return
+		}
+		n = n.children[n.numItems]
+		height--
+	}
+}
+
+func (c *cursor) skipEnded() {
+	for len(c.stack) > 0 {
+		f := c.stack[len(c.stack)-1]
+		if f.i < f.n.numItems {
+			return
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+}
+
+func (c *cursor) skipEndedReverse() {
+	for len(c.stack) > 0 && c.stack[len(c.stack)-1].i < 0 {
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+}
+
+// valid return false when walk is over
+func (c *cursor) valid() bool {
+	return len(c.stack) > 0
+}
+
+// key return current key
+func (c *cursor) key() []byte {
+	f := c.stack[len(c.stack)-1]
+	return f.n.items[f.i].key
+}
+
+// next move to next key in ascending order
+func (c *cursor) next() {
+	f := &c.stack[len(c.stack)-1]
+	f.i++
+	if f.height > 0 {
+		c.pushLeft(f.n.children[f.i], f.height-1)
+	}
+	c.skipEnded()
+}
+
+// prev move to next key in descending order
+func (c *cursor) prev() {
+	f := &c.stack[len(c.stack)-1]
+	if f.height > 0 {
+		child := f.n.children[f.i]
+		f.i--
+		c.pushRight(child, f.height-1)
+	} else {
+		f.i--
+	}
+	c.skipEndedReverse()
+}
@@ -0,0 +1,110 @@
+package btreeset
+
+import "bytes"
+
+// Diff walk a and b in order, calling onAdd for keys only in b
+// and onRemove for keys only in a, walk stops if callback return false
+// Subtrees shared by copies of one tree are skipped without reading
+func Diff(a, b *BTreeSet, onAdd, onRemove func(key []byte) bool) {
+	if a.root == b.root {
+		return
+	}
+	ia, ib := newDiffIter(a), newDiffIter(b)
+	for {
+		sa, ha, ka, oka := ia.peek()
+		sb, hb, kb, okb := ib.peek()
+		switch {
+		case !oka && !okb:
+			return
+		case sa != nil && sa == sb:
+			// same node, same keys
+			ia.next()
+			ib.next()
+		case sa != nil || sb != nil:
+			// open higher subtree first, so shared nodes meet at same level
+			if sa != nil && (sb == nil || ha >= hb) {
+				ia.expand()
+			}
+			if sb != nil && (sa == nil || hb >= ha) {
+				ib.expand()
+			}
+		case !oka:
+			if !onAdd(kb) {
+				return
+			}
+			ib.next()
+		case !okb:
+			if !onRemove(ka) {
+				return
+			}
+			ia.next()
+		default:
+			switch c := bytes.Compare(ka, kb); {
+			case c < 0:
+				if !onRemove(ka) {
+					return
+				}
+				ia.next()
+			case c > 0:
+				if !onAdd(kb) {
+					return
+				}
+				ib.next()
+			default:
+				ia.next()
+				ib.next()
+			}
+		}
+	}
+}
+
+// diffIter walk tree as sequence of keys and not opened subtrees
+type diffIter struct {
+	stack []diffFrame
+}
+
+type diffFrame struct {
+	n      *node
+	height int
+	// leaf: item index, branch: even - child pos/2, odd - item pos/2
+	pos int
+}
+
+func newDiffIter(tr *BTreeSet) *diffIter {
+	it := &diffIter{}
+	if tr.root != nil {
+		it.stack = append(it.stack, diffFrame{n: tr.root, height: tr.height})
+	}
+	return it
+}
+
+// peek return next subtree with its height or next key
+func (it *diffIter) peek() (sub *node, height int, key []byte, ok bool) {
+	for len(it.stack) > 0 {
+		f := &it.stack[len(it.stack)-1]
+		if f.height == 0 {
+			if f.pos < f.n.numItems {
+				return nil, 0, f.n.items[f.pos].key, true
+			}
+		} else if f.pos <= 2*f.n.numItems {
+			if f.pos%2 == 0 {
+				return f.n.children[f.pos/2], f.height - 1, nil, true
+			}
+			return nil, 0, f.n.items[f.pos/2].key, true
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+	return nil, 0, nil, false
+}
+
+// next skip key or whole subtree
+func (it *diffIter) next() {
+	it.stack[len(it.stack)-1].pos++
+}
+
+// expand replace next subtree with its keys and children
+func (it *diffIter) expand() {
+	sub, height, _, _ := it.peek()
+	it.next()
+	it.stack = append(it.stack, diffFrame{n: sub, height: height})
+}
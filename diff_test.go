@@ -0,0 +1,101 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func diffKeys(a, b *BTreeSet) (added, removed []string) {
+	Diff(a, b, func(key []byte) bool {
+		added = append(added, string(key))
+		return true
+	}, func(key []byte) bool {
+		removed = append(removed, string(key))
+		return true
+	})
+	return
+}
+
+func TestDiff(t *testing.T) {
+	var a, b BTreeSet
+	added, removed := diffKeys(&a, &b)
+	assert.Nil(t, added)
+	assert.Nil(t, removed)
+
+	expA, expB := make(map[string]bool), make(map[string]bool)
+	for i := 0; i < 3000; i++ {
+		k := fmt.Sprintf("%05d", rand.Intn(5000))
+		if rand.Intn(2) == 0 {
+			a.Set([]byte(k))
+			expA[k] = true
+		} else {
+			b.Set([]byte(k))
+			expB[k] = true
+		}
+	}
+	var expAdded, expRemoved []string
+	for k := range expB {
+		if !expA[k] {
+			expAdded = append(expAdded, k)
+		}
+	}
+	for k := range expA {
+		if !expB[k] {
+			expRemoved = append(expRemoved, k)
+		}
+	}
+	sort.Strings(expAdded)
+	sort.Strings(expRemoved)
+	added, removed = diffKeys(&a, &b)
+	assert.Equal(t, expAdded, added)
+	assert.Equal(t, expRemoved, removed)
+}
+
+func TestDiffCopy(t *testing.T) {
+	var a BTreeSet
+	for _, k := range randKeys(100000) {
+		a.Set([]byte(k))
+	}
+	b := a.Copy()
+	added, removed := diffKeys(&a, b)
+	assert.Nil(t, added)
+	assert.Nil(t, removed)
+
+	b.Set([]byte("new"))
+	b.Delete([]byte("05000"))
+	b.Delete([]byte("99999"))
+	added, removed = diffKeys(&a, b)
+	assert.Equal(t, []string{"new"}, added)
+	assert.Equal(t, []string{"05000", "99999"}, removed)
+	added, removed = diffKeys(b, &a)
+	assert.Equal(t, []string{"05000", "99999"}, added)
+	assert.Equal(t, []string{"new"}, removed)
+
+	// stop on false
+	var n int
+	Diff(&a, b, func(key []byte) bool {
+		n++
+		return false
+	}, func(key []byte) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, 1, n)
+}
+
+func BenchmarkDiffCopy(b *testing.B) {
+	var tr BTreeSet
+	for _, k := range randKeys(1000000) {
+		tr.Set([]byte(k))
+	}
+	cp := tr.Copy()
+	cp.Set([]byte("new"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Diff(&tr, cp, func(key []byte) bool { return true }, func(key []byte) bool { return true })
+	}
+}
@@ -0,0 +1,659 @@
+package btreeset
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+var (
+	// ErrKeyTooLarge returned when key is longer than MaxKeySize
+	ErrKeyTooLarge = errors.New("btreeset: key too large")
+	// ErrPageSize returned when page can't hold enough keys, or when
+	// key length or keys per page don't fit in 16 bits of page format
+	ErrPageSize = errors.New("btreeset: page size does not fit max key size")
+)
+
+const diskMagic = "BTSD"
+
+const freePage = 0xff
+
+// DiskOptions for OpenDisk
+type DiskOptions struct {
+	// PageSize in bytes, default 4096
+	PageSize int
+	// MaxKeySize in bytes, default 64
+	MaxKeySize int
+	// CacheSize is number of cached pages, default 1024
+	CacheSize int
+}
+
+// DiskBTreeSet is an ordered set of keys, stored as fixed size pages in file
+// Page 0 is header, other pages are nodes or free pages linked in free list
+// DiskBTreeSet is not safe for concurrent use and not crash safe between Sync
+type DiskBTreeSet struct {
+	f         *os.File
+	pageSize  int
+	maxKey    int
+	order     int
+	minItems  int
+	cacheSize int
+
+	root     uint64
+	height   int
+	length   int
+	pages    uint64
+	freeHead uint64
+
+	cache   map[uint64]*dnode
+	lru     *list.List
+	writing bool
+}
+
+type dnode struct {
+	id       uint64
+	keys     [][]byte
+	children []uint64
+	dirty    bool
+	elem     *list.Element
+}
+
+// OpenDisk open or create disk set at path
+// PageSize and MaxKeySize of existing file are read from its header
+func OpenDisk(path string, opt *DiskOptions) (*DiskBTreeSet, error) {
+	d := &DiskBTreeSet{pageSize: 4096, maxKey: 64, cacheSize: 1024}
+	if opt != nil {
+		if opt.PageSize > 0 {
+			d.pageSize = opt.PageSize
+		}
+		if opt.MaxKeySize > 0 {
+			d.maxKey = opt.MaxKeySize
+		}
+		if opt.CacheSize > 0 {
+			d.cacheSize = opt.CacheSize
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	d.f = f
+	st, err := f.Stat()
+	if err == nil {
+		if st.Size() == 0 {
+			d.pages = 1
+			if err = d.layout(); err == nil {
+				err = d.writeHeader()
+			}
+		} else if err = d.readHeader(); err == nil {
+			err = d.layout()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.cache = make(map[uint64]*dnode)
+	d.lru = list.New()
+	return d, nil
+}
+
+// layout compute keys per page, page store key length and number of
+// keys as uint16
+func (d *DiskBTreeSet) layout() error {
+	if d.pageSize < 3+8 || d.maxKey > math.MaxUint16 {
+		return ErrPageSize
+	}
+	// flag, numItems, keys with length, children
+	d.order = (d.pageSize - 3 - 8) / (2 + d.maxKey + 8)
+	d.minItems = d.order * 40 / 100
+	if d.order < 4 || d.order > math.MaxUint16 {
+		return ErrPageSize
+	}
+	return nil
+}
+
+// header: magic | pageSize | maxKey | root | height | length | pages | freeHead
+func (d *DiskBTreeSet) writeHeader() error {
+	p := make([]byte, d.pageSize)
+	copy(p, diskMagic)
+	for i, v := range []uint64{uint64(d.pageSize), uint64(d.maxKey), d.root,
+		uint64(d.height), uint64(d.length), d.pages, d.freeHead} {
+		binary.BigEndian.PutUint64(p[4+i*8:], v)
+	}
+	_, err := d.f.WriteAt(p, 0)
+	return err
+}
+
+func (d *DiskBTreeSet) readHeader() error {
+	p := make([]byte, 4+7*8)
+	if _, err := d.f.ReadAt(p, 0); err != nil {
+		return err
+	}
+	if string(p[:4]) != diskMagic {
+		return ErrCorrupted
+	}
+	v := func(i int) uint64 { return binary.BigEndian.Uint64(p[4+i*8:]) }
+	d.pageSize, d.maxKey, d.root = int(v(0)), int(v(1)), v(2)
+	d.height, d.length, d.pages, d.freeHead = int(v(3)), int(v(4)), v(5), v(6)
+	return nil
+}
+
+func (d *DiskBTreeSet) writeNode(n *dnode) error {
+	p := make([]byte, d.pageSize)
+	if len(n.children) == 0 {
+		p[0] = 1
+	}
+	binary.BigEndian.PutUint16(p[1:], uint16(len(n.keys)))
+	off := 3
+	for _, key := range n.keys {
+		binary.BigEndian.PutUint16(p[off:], uint16(len(key)))
+		off += 2 + copy(p[off+2:], key)
+	}
+	for _, id := range n.children {
+		binary.BigEndian.PutUint64(p[off:], id)
+		off += 8
+	}
+	if _, err := d.f.WriteAt(p, int64(n.id)*int64(d.pageSize)); err != nil {
+		return err
+	}
+	n.dirty = false
+	return nil
+}
+
+func (d *DiskBTreeSet) readPage(id uint64) ([]byte, error) {
+	p := make([]byte, d.pageSize)
+	_, err := d.f.ReadAt(p, int64(id)*int64(d.pageSize))
+	if err == io.EOF {
+		err = ErrCorrupted
+	}
+	return p, err
+}
+
+// node return cached or loaded node
+func (d *DiskBTreeSet) node(id uint64) (*dnode, error) {
+	if n, ok := d.cache[id]; ok {
+		d.lru.MoveToFront(n.elem)
+		return n, nil
+	}
+	p, err := d.readPage(id)
+	if err != nil {
+		return nil, err
+	}
+	if p[0] == freePage {
+		return nil, ErrCorrupted
+	}
+	n := &dnode{id: id, keys: make([][]byte, binary.BigEndian.Uint16(p[1:]))}
+	off := 3
+	for i := range n.keys {
+		size := int(binary.BigEndian.Uint16(p[off:]))
+		n.keys[i] = p[off+2 : off+2+size : off+2+size]
+		off += 2 + size
+	}
+	if p[0] == 0 {
+		n.children = make([]uint64, len(n.keys)+1)
+		for i := range n.children {
+			n.children[i] = binary.BigEndian.Uint64(p[off:])
+			off += 8
+		}
+	}
+	if err = d.cachePut(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (d *DiskBTreeSet) cachePut(n *dnode) error {
+	n.elem = d.lru.PushFront(n)
+	d.cache[n.id] = n
+	if !d.writing {
+		// readers don't modify nodes, so evicted node may still be read
+		return d.trim()
+	}
+	return nil
+}
+
+// trim write and evict least recently used pages above CacheSize
+func (d *DiskBTreeSet) trim() error {
+	for d.lru.Len() > d.cacheSize {
+		n := d.lru.Back().Value.(*dnode)
+		if n.dirty {
+			if err := d.writeNode(n); err != nil {
+				return err
+			}
+		}
+		d.lru.Remove(n.elem)
+		delete(d.cache, n.id)
+	}
+	return nil
+}
+
+func (d *DiskBTreeSet) alloc() (*dnode, error) {
+	n := &dnode{dirty: true}
+	if d.freeHead != 0 {
+		p, err := d.readPage(d.freeHead)
+		if err != nil {
+			return nil, err
+		}
+		n.id = d.freeHead
+		d.freeHead = binary.BigEndian.Uint64(p[1:])
+	} else {
+		n.id = d.pages
+		d.pages++
+	}
+	return n, d.cachePut(n)
+}
+
+// free put page in free list
+func (d *DiskBTreeSet) free(n *dnode) error {
+	d.lru.Remove(n.elem)
+	delete(d.cache, n.id)
+	p := make([]byte, d.pageSize)
+	p[0] = freePage
+	binary.BigEndian.PutUint64(p[1:], d.freeHead)
+	if _, err := d.f.WriteAt(p, int64(n.id)*int64(d.pageSize)); err != nil {
+		return err
+	}
+	d.freeHead = n.id
+	return nil
+}
+
+func (n *dnode) find(key []byte) (index int, found bool) {
+	index = sort.Search(len(n.keys), func(i int) bool {
+		return bytes.Compare(n.keys[i], key) >= 0
+	})
+	return index, index < len(n.keys) && bytes.Equal(n.keys[index], key)
+}
+
+// Set or replace a key
+func (d *DiskBTreeSet) Set(key []byte) (replaced bool, err error) {
+	if len(key) > d.maxKey {
+		return false, ErrKeyTooLarge
+	}
+	key = append([]byte(nil), key...)
+	d.writing = true
+	defer d.endWrite(&err)
+	if d.root == 0 {
+		n, err := d.alloc()
+		if err != nil {
+			return false, err
+		}
+		n.keys = [][]byte{key}
+		d.root, d.height, d.length = n.id, 0, 1
+		return false, nil
+	}
+	root, err := d.node(d.root)
+	if err != nil {
+		return
+	}
+	replaced, err = d.set(root, key, d.height)
+	if err != nil || replaced {
+		return
+	}
+	if len(root.keys) == d.order {
+		right, median, err := d.split(root, d.height)
+		if err != nil {
+			return false, err
+		}
+		n, err := d.alloc()
+		if err != nil {
+			return false, err
+		}
+		n.keys = [][]byte{median}
+		n.children = []uint64{root.id, right.id}
+		d.root = n.id
+		d.height++
+	}
+	d.length++
+	return
+}
+
+func (d *DiskBTreeSet) endWrite(err *error) {
+	d.writing = false
+	if terr := d.trim(); *err == nil {
+		*err = terr
+	}
+}
+
+func (d *DiskBTreeSet) split(n *dnode, height int) (right *dnode, median []byte, err error) {
+	right, err = d.alloc()
+	if err != nil {
+		return
+	}
+	mid := d.order / 2
+	median = n.keys[mid]
+	right.keys = append([][]byte(nil), n.keys[mid+1:]...)
+	n.keys = n.keys[:mid:mid]
+	if height > 0 {
+		right.children = append([]uint64(nil), n.children[mid+1:]...)
+		n.children = n.children[: mid+1 : mid+1]
+	}
+	n.dirty = true
+	return
+}
+
+func (d *DiskBTreeSet) set(n *dnode, key []byte, height int) (replaced bool, err error) {
+	i, found := n.find(key)
+	if found {
+		return true, nil
+	}
+	if height == 0 {
+		n.keys = append(n.keys, nil)
+		copy(n.keys[i+1:], n.keys[i:])
+		n.keys[i] = key
+		n.dirty = true
+		return false, nil
+	}
+	child, err := d.node(n.children[i])
+	if err != nil {
+		return
+	}
+	replaced, err = d.set(child, key, height-1)
+	if err != nil || replaced {
+		return
+	}
+	if len(child.keys) == d.order {
+		right, median, err := d.split(child, height-1)
+		if err != nil {
+			return false, err
+		}
+		n.keys = append(n.keys, nil)
+		copy(n.keys[i+1:], n.keys[i:])
+		n.keys[i] = median
+		n.children = append(n.children, 0)
+		copy(n.children[i+2:], n.children[i+1:])
+		n.children[i+1] = right.id
+		n.dirty = true
+	}
+	return
+}
+
+// Has return true if key exists
+func (d *DiskBTreeSet) Has(key []byte) (bool, error) {
+	if d.root == 0 {
+		return false, nil
+	}
+	id := d.root
+	for height := d.height; ; height-- {
+		n, err := d.node(id)
+		if err != nil {
+			return false, err
+		}
+		i, found := n.find(key)
+		if found {
+			return true, nil
+		}
+		if height == 0 {
+			return false, nil
+		}
+		id = n.children[i]
+	}
+}
+
+// Len returns the number of items in the set
+func (d *DiskBTreeSet) Len() int {
+	return d.length
+}
+
+// Delete a key
+func (d *DiskBTreeSet) Delete(key []byte) (deleted bool, err error) {
+	if d.root == 0 {
+		return
+	}
+	d.writing = true
+	defer d.endWrite(&err)
+	root, err := d.node(d.root)
+	if err != nil {
+		return
+	}
+	_, deleted, err = d.delete(root, false, key, d.height)
+	if err != nil || !deleted {
+		return
+	}
+	d.length--
+	if d.length == 0 {
+		d.root, d.height = 0, 0
+		return true, d.free(root)
+	}
+	if len(root.keys) == 0 {
+		d.root = root.children[0]
+		d.height--
+		return true, d.free(root)
+	}
+	return
+}
+
+func (d *DiskBTreeSet) delete(n *dnode, max bool, key []byte, height int) (prev []byte, deleted bool, err error) {
+	var i int
+	var found bool
+	if max {
+		i, found = len(n.keys)-1, true
+	} else {
+		i, found = n.find(key)
+	}
+	if height == 0 {
+		if found {
+			prev = n.keys[i]
+			n.keys = append(n.keys[:i], n.keys[i+1:]...)
+			n.dirty = true
+			return prev, true, nil
+		}
+		return nil, false, nil
+	}
+	if found && max {
+		i++
+	}
+	child, err := d.node(n.children[i])
+	if err != nil {
+		return
+	}
+	if found && !max {
+		prev = n.keys[i]
+		var maxKey []byte
+		if maxKey, _, err = d.delete(child, true, nil, height-1); err != nil {
+			return
+		}
+		n.keys[i] = maxKey
+		n.dirty = true
+		deleted = true
+	} else {
+		prev, deleted, err = d.delete(child, max, key, height-1)
+	}
+	if err != nil || !deleted || len(child.keys) >= d.minItems {
+		return
+	}
+	if i == len(n.keys) {
+		i--
+	}
+	left, err := d.node(n.children[i])
+	if err != nil {
+		return
+	}
+	right, err := d.node(n.children[i+1])
+	if err != nil {
+		return
+	}
+	n.dirty, left.dirty, right.dirty = true, true, true
+	if len(left.keys)+len(right.keys)+1 < d.order {
+		// merge left + item + right
+		left.keys = append(append(left.keys, n.keys[i]), right.keys...)
+		if height > 1 {
+			left.children = append(left.children, right.children...)
+		}
+		n.keys = append(n.keys[:i], n.keys[i+1:]...)
+		n.children = append(n.children[:i+1], n.children[i+2:]...)
+		err = d.free(right)
+	} else if len(left.keys) > len(right.keys) {
+		// move left -> right
+		right.keys = append([][]byte{n.keys[i]}, right.keys...)
+		if height > 1 {
+			last := len(left.children) - 1
+			right.children = append([]uint64{left.children[last]}, right.children...)
+			left.children = left.children[:last]
+		}
+		n.keys[i] = left.keys[len(left.keys)-1]
+		left.keys = left.keys[:len(left.keys)-1]
+	} else {
+		// move right -> left
+		left.keys = append(left.keys, n.keys[i])
+		if height > 1 {
+			left.children = append(left.children, right.children[0])
+			right.children = append([]uint64(nil), right.children[1:]...)
+		}
+		n.keys[i] = right.keys[0]
+		right.keys = append([][]byte(nil), right.keys[1:]...)
+	}
+	return
+}
+
+// Ascend the set within the range [pivot, last]
+func (d *DiskBTreeSet) Ascend(pivot []byte, iter func(key []byte) bool) error {
+	if d.root == 0 {
+		return nil
+	}
+	_, err := d.ascend(d.root, pivot, iter, d.height)
+	return err
+}
+
+func (d *DiskBTreeSet) ascend(id uint64, pivot []byte, iter func(key []byte) bool, height int) (bool, error) {
+	n, err := d.node(id)
+	if err != nil {
+		return false, err
+	}
+	i, found := n.find(pivot)
+	if !found && height > 0 {
+		if ok, err := d.ascend(n.children[i], pivot, iter, height-1); !ok || err != nil {
+			return false, err
+		}
+	}
+	for ; i < len(n.keys); i++ {
+		if !iter(n.keys[i]) {
+			return false, nil
+		}
+		if height > 0 {
+			if ok, err := d.scan(n.children[i+1], iter, height-1); !ok || err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// Scan all items in set
+func (d *DiskBTreeSet) Scan(iter func(key []byte) bool) error {
+	if d.root == 0 {
+		return nil
+	}
+	_, err := d.scan(d.root, iter, d.height)
+	return err
+}
+
+func (d *DiskBTreeSet) scan(id uint64, iter func(key []byte) bool, height int) (bool, error) {
+	n, err := d.node(id)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i <= len(n.keys); i++ {
+		if height > 0 {
+			if ok, err := d.scan(n.children[i], iter, height-1); !ok || err != nil {
+				return false, err
+			}
+		}
+		if i < len(n.keys) && !iter(n.keys[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Descend the set within the range [pivot, first]
+func (d *DiskBTreeSet) Descend(pivot []byte, iter func(key []byte) bool) error {
+	if d.root == 0 {
+		return nil
+	}
+	_, err := d.descend(d.root, pivot, iter, d.height)
+	return err
+}
+
+func (d *DiskBTreeSet) descend(id uint64, pivot []byte, iter func(key []byte) bool, height int) (bool, error) {
+	n, err := d.node(id)
+	if err != nil {
+		return false, err
+	}
+	i, found := n.find(pivot)
+	if !found {
+		if height > 0 {
+			if ok, err := d.descend(n.children[i], pivot, iter, height-1); !ok || err != nil {
+				return false, err
+			}
+		}
+		i--
+	}
+	for ; i >= 0; i-- {
+		if !iter(n.keys[i]) {
+			return false, nil
+		}
+		if height > 0 {
+			if ok, err := d.reverse(n.children[i], iter, height-1); !ok || err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// Reverse all items in set
+func (d *DiskBTreeSet) Reverse(iter func(key []byte) bool) error {
+	if d.root == 0 {
+		return nil
+	}
+	_, err := d.reverse(d.root, iter, d.height)
+	return err
+}
+
+func (d *DiskBTreeSet) reverse(id uint64, iter func(key []byte) bool, height int) (bool, error) {
+	n, err := d.node(id)
+	if err != nil {
+		return false, err
+	}
+	for i := len(n.keys); i >= 0; i-- {
+		if i < len(n.keys) && !iter(n.keys[i]) {
+			return false, nil
+		}
+		if height > 0 {
+			if ok, err := d.reverse(n.children[i], iter, height-1); !ok || err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// Sync write dirty pages and header, then fsync file
+func (d *DiskBTreeSet) Sync() error {
+	for e := d.lru.Front(); e != nil; e = e.Next() {
+		if n := e.Value.(*dnode); n.dirty {
+			if err := d.writeNode(n); err != nil {
+				return err
+			}
+		}
+	}
+	if err := d.writeHeader(); err != nil {
+		return err
+	}
+	return d.f.Sync()
+}
+
+// Close sync and close file
+func (d *DiskBTreeSet) Close() error {
+	err := d.Sync()
+	if cerr := d.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
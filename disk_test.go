@@ -0,0 +1,113 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskBTreeSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.db")
+	opt := &DiskOptions{PageSize: 256, MaxKeySize: 16, CacheSize: 8}
+	d, err := OpenDisk(path, opt)
+	assert.NoError(t, err)
+	_, err = d.Set(make([]byte, 17))
+	assert.Equal(t, ErrKeyTooLarge, err)
+
+	N := 3000
+	exp := make(map[string]bool)
+	for _, i := range rand.Perm(N) {
+		key := fmt.Sprintf("%05d", i)
+		replaced, err := d.Set([]byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, false, replaced)
+		exp[key] = true
+	}
+	for i := 0; i < N; i++ {
+		key := fmt.Sprintf("%05d", rand.Intn(N))
+		deleted, err := d.Delete([]byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, exp[key], deleted)
+		delete(exp, key)
+	}
+	assert.Equal(t, len(exp), d.Len())
+	assert.NoError(t, d.Close())
+
+	d, err = OpenDisk(path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, len(exp), d.Len())
+	var keys []string
+	for key := range exp {
+		keys = append(keys, key)
+		ok, err := d.Has([]byte(key))
+		assert.NoError(t, err)
+		assert.Equal(t, true, ok)
+	}
+	sort.Strings(keys)
+	var all []string
+	assert.NoError(t, d.Scan(func(key []byte) bool {
+		all = append(all, string(key))
+		return true
+	}))
+	assert.Equal(t, keys, all)
+
+	pivot := keys[len(keys)/2]
+	all = nil
+	d.Ascend([]byte(pivot), func(key []byte) bool {
+		all = append(all, string(key))
+		return true
+	})
+	assert.Equal(t, keys[len(keys)/2:], all)
+	all = nil
+	d.Descend([]byte(pivot+"x"), func(key []byte) bool {
+		all = append(all, string(key))
+		return len(all) < 3
+	})
+	assert.Equal(t, []string{keys[len(keys)/2], keys[len(keys)/2-1], keys[len(keys)/2-2]}, all)
+
+	// pages released by delete are reused
+	for _, key := range keys {
+		_, err = d.Delete([]byte(key))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 0, d.Len())
+	assert.NoError(t, d.Sync())
+	st, _ := os.Stat(path)
+	for _, key := range keys {
+		_, err = d.Set([]byte(key))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, d.Close())
+	st2, _ := os.Stat(path)
+	assert.Equal(t, st.Size(), st2.Size())
+}
+
+func TestDiskBTreeSetLimits(t *testing.T) {
+	dir := t.TempDir()
+	_, err := OpenDisk(filepath.Join(dir, "a.db"), &DiskOptions{PageSize: 1 << 20, MaxKeySize: 70000})
+	assert.Equal(t, ErrPageSize, err)
+	_, err = OpenDisk(filepath.Join(dir, "b.db"), &DiskOptions{PageSize: 1 << 22, MaxKeySize: 1})
+	assert.Equal(t, ErrPageSize, err)
+
+	// longest key which fits uint16 length survive reopen
+	path := filepath.Join(dir, "c.db")
+	opt := &DiskOptions{PageSize: 1 << 20, MaxKeySize: 65535}
+	d, err := OpenDisk(path, opt)
+	assert.NoError(t, err)
+	key := make([]byte, 65535)
+	key[0] = 1
+	_, err = d.Set(key)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Close())
+	d, err = OpenDisk(path, opt)
+	assert.NoError(t, err)
+	has, err := d.Has(key)
+	assert.NoError(t, err)
+	assert.Equal(t, true, has)
+	assert.NoError(t, d.Close())
+}
@@ -0,0 +1,117 @@
+package btreeset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatKey show printable keys as strings, 8 byte keys as uint64
+// like KeyToBinary of int, other keys as hex
+func FormatKey(key []byte) string {
+	printable := utf8.Valid(key)
+	for _, r := range string(key) {
+		if !strconv.IsPrint(r) {
+			printable = false
+			break
+		}
+	}
+	switch {
+	case printable:
+		return string(key)
+	case len(key) == 8:
+		return strconv.FormatUint(binary.BigEndian.Uint64(key), 10)
+	}
+	return "0x" + hex.EncodeToString(key)
+}
+
+type dumpNode struct {
+	Height   int         `json:"height"`
+	Items    []string    `json:"items"`
+	Children []*dumpNode `json:"children,omitempty"`
+}
+
+type dumpTree struct {
+	Height int       `json:"height"`
+	Length int       `json:"length"`
+	Root   *dumpNode `json:"root"`
+}
+
+func (n *node) dump(height int, format func(key []byte) string) *dumpNode {
+	d := &dumpNode{Height: height, Items: make([]string, n.numItems)}
+	for i := 0; i < n.numItems; i++ {
+		d.Items[i] = format(n.items[i].key)
+	}
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			d.Children = append(d.Children, n.children[i].dump(height-1, format))
+		}
+	}
+	return d
+}
+
+// WriteJSON write tree structure as JSON, nil format is FormatKey
+func (tr *BTreeSet) WriteJSON(w io.Writer, format func(key []byte) string) error {
+	if format == nil {
+		format = FormatKey
+	}
+	d := dumpTree{Height: tr.height, Length: tr.length}
+	if tr.root != nil {
+		d.Root = tr.root.dump(tr.height, format)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// dotEscape escape special characters of record label
+var dotEscape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `|`, `\|`,
+	`{`, `\{`, `}`, `\}`, `<`, `\<`, `>`, `\>`, "\n", `\n`)
+
+// WriteDOT write tree structure as Graphviz graph, nil format is FormatKey
+func (tr *BTreeSet) WriteDOT(w io.Writer, format func(key []byte) string) error {
+	if format == nil {
+		format = FormatKey
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph btreeset {\n\tnode [shape=record];\n")
+	if tr.root != nil {
+		var id int
+		tr.root.dot(bw, &id, tr.height, format)
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// dot write node as record <c0>|key0|<c1>|key1|<c2>, return its id
+func (n *node) dot(w io.Writer, id *int, height int, format func(key []byte) string) int {
+	self := *id
+	*id++
+	var label strings.Builder
+	for i := 0; i < n.numItems; i++ {
+		if height > 0 {
+			fmt.Fprintf(&label, "<c%d>|", i)
+		}
+		label.WriteString(dotEscape.Replace(format(n.items[i].key)))
+		if i < n.numItems-1 || height > 0 {
+			label.WriteString("|")
+		}
+	}
+	if height > 0 {
+		fmt.Fprintf(&label, "<c%d>", n.numItems)
+	}
+	fmt.Fprintf(w, "\tn%d [label=\"%s\"];\n", self, label.String())
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			child := n.children[i].dot(w, id, height-1, format)
+			fmt.Fprintf(w, "\tn%d:c%d -> n%d;\n", self, i, child)
+		}
+	}
+	return self
+}
@@ -0,0 +1,55 @@
+package btreeset
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatKey(t *testing.T) {
+	assert.Equal(t, "user:1", FormatKey([]byte("user:1")))
+	key, _ := KeyToBinary(42)
+	assert.Equal(t, "42", FormatKey(key))
+	assert.Equal(t, "0x0001", FormatKey([]byte{0, 1}))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 1000; i++ {
+		key, _ := KeyToBinary(i)
+		tr.Set(key)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, tr.WriteJSON(&buf, nil))
+	var d dumpTree
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &d))
+	assert.Equal(t, 1, d.Height)
+	assert.Equal(t, 1000, d.Length)
+	assert.Equal(t, d.Root.Height, 1)
+	assert.Equal(t, len(d.Root.Items)+1, len(d.Root.Children))
+	assert.Equal(t, "0", d.Root.Children[0].Items[0])
+
+	buf.Reset()
+	var empty BTreeSet
+	assert.NoError(t, empty.WriteJSON(&buf, nil))
+	assert.Equal(t, true, strings.Contains(buf.String(), `"root": null`))
+}
+
+func TestWriteDOT(t *testing.T) {
+	var tr BTreeSet
+	for _, k := range randKeys(600) {
+		tr.Set([]byte(k))
+	}
+	tr.Set([]byte(`a|"b"`))
+	var buf bytes.Buffer
+	assert.NoError(t, tr.WriteDOT(&buf, nil))
+	dot := buf.String()
+	assert.Equal(t, true, strings.HasPrefix(dot, "digraph btreeset {"))
+	assert.Equal(t, true, strings.Contains(dot, `a\|\"b\"`))
+	st := tr.Stats()
+	assert.Equal(t, st.Nodes, strings.Count(dot, "[label="))
+	assert.Equal(t, st.Nodes-1, strings.Count(dot, " -> "))
+}
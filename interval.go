@@ -0,0 +1,153 @@
+package btreeset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// IntervalSet is a set of half-open intervals [lo, hi)
+// Every node keeps max hi of its subtree, so overlap queries skip
+// subtrees which end before the query
+type IntervalSet struct {
+	tree BTreeSet
+	// Merge overlapping and adjacent intervals on Insert
+	Merge bool
+}
+
+func int64ToBinary(v int64) []byte {
+	p := make([]byte, 8)
+	binary.BigEndian.PutUint64(p, uint64(v)^(1<<63))
+	return p
+}
+
+func binaryToInt64(p []byte) int64 {
+	return int64(binary.BigEndian.Uint64(p) ^ (1 << 63))
+}
+
+func intervalKey(lo, hi int64) []byte {
+	return append(int64ToBinary(lo), int64ToBinary(hi)...)
+}
+
+// maxEnd store in node max hi of all intervals in subtree
+func maxEnd(n *node, height int) {
+	var max []byte
+	for i := 0; i < n.numItems; i++ {
+		if hi := n.items[i].key[8:]; bytes.Compare(hi, max) > 0 {
+			max = hi
+		}
+	}
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			if bytes.Compare(n.children[i].aug, max) > 0 {
+				max = n.children[i].aug
+			}
+		}
+	}
+	n.aug = max
+}
+
+// Insert interval [lo, hi), return false if interval is empty or exists
+func (s *IntervalSet) Insert(lo, hi int64) (inserted bool) {
+	if lo >= hi {
+		return false
+	}
+	s.tree.augment = maxEnd
+	if s.Merge {
+		var found [][2]int64
+		qlo, qhi := lo, hi
+		if qlo > math.MinInt64 {
+			qlo--
+		}
+		if qhi < math.MaxInt64 {
+			qhi++
+		}
+		s.Overlapping(qlo, qhi, func(l, h int64) bool {
+			found = append(found, [2]int64{l, h})
+			return true
+		})
+		if len(found) == 1 && found[0][0] <= lo && found[0][1] >= hi {
+			return false
+		}
+		for _, f := range found {
+			s.tree.Delete(intervalKey(f[0], f[1]))
+			if f[0] < lo {
+				lo = f[0]
+			}
+			if f[1] > hi {
+				hi = f[1]
+			}
+		}
+	}
+	return !s.tree.Set(intervalKey(lo, hi))
+}
+
+// Remove interval [lo, hi)
+func (s *IntervalSet) Remove(lo, hi int64) (removed bool) {
+	return s.tree.Delete(intervalKey(lo, hi))
+}
+
+// Len returns the number of intervals
+func (s *IntervalSet) Len() int {
+	return s.tree.Len()
+}
+
+// Scan all intervals ordered by lo, hi
+func (s *IntervalSet) Scan(iter func(lo, hi int64) bool) {
+	s.tree.Scan(func(key []byte) bool {
+		return iter(binaryToInt64(key[:8]), binaryToInt64(key[8:]))
+	})
+}
+
+// Overlapping iterate intervals which overlap [lo, hi)
+func (s *IntervalSet) Overlapping(lo, hi int64, iter func(lo, hi int64) bool) {
+	if s.tree.root != nil && lo < hi {
+		s.tree.root.overlapping(int64ToBinary(lo), int64ToBinary(hi), iter, s.tree.height)
+	}
+}
+
+// Stab iterate intervals which contain point p
+func (s *IntervalSet) Stab(p int64, iter func(lo, hi int64) bool) {
+	if s.tree.root != nil {
+		// [p, p+1) is empty for MaxInt64, which is never contained
+		if p == math.MaxInt64 {
+			return
+		}
+		s.tree.root.overlapping(int64ToBinary(p), int64ToBinary(p+1), iter, s.tree.height)
+	}
+}
+
+// Overlaps return true if [lo, hi) overlap any interval
+func (s *IntervalSet) Overlaps(lo, hi int64) (overlaps bool) {
+	s.Overlapping(lo, hi, func(int64, int64) bool {
+		overlaps = true
+		return false
+	})
+	return
+}
+
+func (n *node) overlapping(lo, hi []byte, iter func(lo, hi int64) bool, height int) bool {
+	if bytes.Compare(n.aug, lo) <= 0 {
+		// all intervals in subtree end before lo
+		return true
+	}
+	for i := 0; i < n.numItems; i++ {
+		if height > 0 && !n.children[i].overlapping(lo, hi, iter, height-1) {
+			return false
+		}
+		key := n.items[i].key
+		if bytes.Compare(key[:8], hi) >= 0 {
+			// all next intervals start after hi
+			return false
+		}
+		if bytes.Compare(key[8:], lo) > 0 {
+			if !iter(binaryToInt64(key[:8]), binaryToInt64(key[8:])) {
+				return false
+			}
+		}
+	}
+	if height > 0 {
+		return n.children[n.numItems].overlapping(lo, hi, iter, height-1)
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package btreeset
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalSet(t *testing.T) {
+	var s IntervalSet
+	assert.Equal(t, false, s.Insert(5, 5))
+	assert.Equal(t, true, s.Insert(0, 10))
+	assert.Equal(t, true, s.Insert(-5, 2))
+	assert.Equal(t, true, s.Insert(20, 30))
+	assert.Equal(t, false, s.Insert(20, 30))
+
+	var got [][2]int64
+	s.Stab(1, func(lo, hi int64) bool {
+		got = append(got, [2]int64{lo, hi})
+		return true
+	})
+	assert.Equal(t, [][2]int64{{-5, 2}, {0, 10}}, got)
+
+	assert.Equal(t, true, s.Overlaps(9, 11))
+	assert.Equal(t, false, s.Overlaps(10, 20))
+	assert.Equal(t, true, s.Remove(0, 10))
+	assert.Equal(t, false, s.Overlaps(9, 11))
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestIntervalSetMerge(t *testing.T) {
+	s := IntervalSet{Merge: true}
+	s.Insert(0, 10)
+	s.Insert(20, 30)
+	s.Insert(10, 12) // adjacent
+	s.Insert(25, 40) // overlap
+	assert.Equal(t, false, s.Insert(1, 5))
+	var got [][2]int64
+	s.Scan(func(lo, hi int64) bool {
+		got = append(got, [2]int64{lo, hi})
+		return true
+	})
+	assert.Equal(t, [][2]int64{{0, 12}, {20, 40}}, got)
+
+	s.Insert(5, 25)
+	got = nil
+	s.Scan(func(lo, hi int64) bool {
+		got = append(got, [2]int64{lo, hi})
+		return true
+	})
+	assert.Equal(t, [][2]int64{{0, 40}}, got)
+}
+
+func TestIntervalSetRandom(t *testing.T) {
+	var s IntervalSet
+	var all [][2]int64
+	add := func(lo, hi int64) {
+		if s.Insert(lo, hi) {
+			all = append(all, [2]int64{lo, hi})
+		}
+	}
+	for i := 0; i < 5000; i++ {
+		lo := rand.Int63n(100000)
+		add(lo, lo+1+rand.Int63n(500))
+	}
+	// remove some, so rebalancing must keep max ends
+	rand.Shuffle(len(all), func(i, j int) { all[i], all[j] = all[j], all[i] })
+	for _, iv := range all[:len(all)/2] {
+		assert.Equal(t, true, s.Remove(iv[0], iv[1]))
+	}
+	all = all[len(all)/2:]
+	sort.Slice(all, func(i, j int) bool {
+		if all[i][0] == all[j][0] {
+			return all[i][1] < all[j][1]
+		}
+		return all[i][0] < all[j][0]
+	})
+	for i := 0; i < 200; i++ {
+		lo := rand.Int63n(101000)
+		hi := lo + 1 + rand.Int63n(1000)
+		var exp, got [][2]int64
+		for _, iv := range all {
+			if iv[0] < hi && iv[1] > lo {
+				exp = append(exp, iv)
+			}
+		}
+		s.Overlapping(lo, hi, func(l, h int64) bool {
+			got = append(got, [2]int64{l, h})
+			return true
+		})
+		assert.Equal(t, exp, got)
+	}
+}
@@ -0,0 +1,98 @@
+package btreeset
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicSet is a set for single writer and many readers
+// Writer change own copy-on-write tree and publish read-only copy of it,
+// so readers never lock and see a tree which is never changed
+type AtomicSet struct {
+	mu   sync.Mutex // serialize writers
+	w    BTreeSet
+	root atomic.Pointer[BTreeSet]
+}
+
+var emptySet = &BTreeSet{}
+
+// publish read-only copy of writer tree
+func (s *AtomicSet) publish() {
+	s.root.Store(s.w.Copy())
+}
+
+// Load return current tree, it must not be changed
+func (s *AtomicSet) Load() *BTreeSet {
+	if tr := s.root.Load(); tr != nil {
+		return tr
+	}
+	return emptySet
+}
+
+// Set a key and publish it
+func (s *AtomicSet) Set(key []byte) (replaced bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if replaced = s.w.Set(key); !replaced {
+		s.publish()
+	}
+	return
+}
+
+// Delete a key and publish it
+func (s *AtomicSet) Delete(key []byte) (deleted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if deleted = s.w.Delete(key); deleted {
+		s.publish()
+	}
+	return
+}
+
+// Batch return new batch of operations
+// Commit publish all operations at once, so nodes on path are copied
+// once per batch instead of once per key
+func (s *AtomicSet) Batch() *Batch {
+	return &Batch{commit: func(ops []batchOp) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.w.apply(ops) {
+			s.publish()
+		}
+	}}
+}
+
+// Has return true if key exists
+func (s *AtomicSet) Has(key []byte) bool {
+	return s.Load().Has(key)
+}
+
+// Len returns the number of items
+func (s *AtomicSet) Len() int {
+	return s.Load().Len()
+}
+
+// Scan all items
+func (s *AtomicSet) Scan(iter func(key []byte) bool) {
+	s.Load().Scan(iter)
+}
+
+// Reverse all items
+func (s *AtomicSet) Reverse(iter func(key []byte) bool) {
+	s.Load().Reverse(iter)
+}
+
+// Ascend within the range [pivot, last]
+func (s *AtomicSet) Ascend(pivot []byte, iter func(key []byte) bool) {
+	s.Load().Ascend(pivot, iter)
+}
+
+// Descend within the range [pivot, first]
+func (s *AtomicSet) Descend(pivot []byte, iter func(key []byte) bool) {
+	s.Load().Descend(pivot, iter)
+}
+
+// AscendPrefix ascend keys with prefix
+func (s *AtomicSet) AscendPrefix(prefix []byte, iter func(key []byte) bool) {
+	s.Load().AscendPrefix(prefix, iter)
+}
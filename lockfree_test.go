@@ -0,0 +1,72 @@
+package btreeset
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicSet(t *testing.T) {
+	var s AtomicSet
+	assert.Equal(t, false, s.Has([]byte("a")))
+	assert.Equal(t, 0, s.Len())
+	assert.Equal(t, false, s.Set([]byte("a")))
+	assert.Equal(t, true, s.Set([]byte("a")))
+	assert.Equal(t, true, s.Has([]byte("a")))
+
+	snap := s.Load()
+	assert.Equal(t, true, s.Delete([]byte("a")))
+	assert.Equal(t, false, s.Delete([]byte("a")))
+	assert.Equal(t, false, s.Has([]byte("a")))
+	// loaded tree is not changed by writer
+	assert.Equal(t, true, snap.Has([]byte("a")))
+	assert.NoError(t, snap.Validate())
+
+	b := s.Batch()
+	for i := 0; i < 1000; i++ {
+		b.Set([]byte(fmt.Sprintf("%04d", i)))
+	}
+	assert.Equal(t, 0, s.Len())
+	b.Commit()
+	assert.Equal(t, 1000, s.Len())
+	assert.NoError(t, s.Load().Validate())
+}
+
+func TestAtomicSetConcurrent(t *testing.T) {
+	var s AtomicSet
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// keys are added in batches of 10, so every batch is seen whole
+				n := 0
+				s.Scan(func(key []byte) bool {
+					n++
+					return true
+				})
+				assert.Equal(t, 0, n%10)
+				s.Has([]byte("0500"))
+			}
+		}()
+	}
+	for i := 0; i < 2000; i += 10 {
+		b := s.Batch()
+		for j := i; j < i+10; j++ {
+			b.Set([]byte(fmt.Sprintf("%04d", j)))
+		}
+		b.Commit()
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, 2000, s.Len())
+}
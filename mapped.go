@@ -0,0 +1,234 @@
+package btreeset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+const mappedMagic = "BTSM"
+
+// keys per block, first key of every block is a separator in index
+const mappedBlock = 64
+
+// MappedSet is an immutable set, read directly from memory mapped file
+// Layout: header | blocks of keys (uvarint len | key) | block offsets
+// Keys passed to iterators point into mapping and valid until Close
+type MappedSet struct {
+	data   []byte
+	count  int
+	blocks int
+	index  []byte
+	unmap  func() error
+}
+
+// WriteMapped write set in MappedSet format
+func WriteMapped(w io.Writer, tr *BTreeSet) error {
+	// header: magic | count | blocks | index offset
+	blocks := (tr.Len() + mappedBlock - 1) / mappedBlock
+	hdr := make([]byte, 4+3*8)
+	copy(hdr, mappedMagic)
+	binary.BigEndian.PutUint64(hdr[4:], uint64(tr.Len()))
+	binary.BigEndian.PutUint64(hdr[12:], uint64(blocks))
+	offsets := make([]byte, 0, blocks*8)
+	off := uint64(len(hdr))
+	var buf []byte
+	var i int
+	tr.Scan(func(key []byte) bool {
+		if i%mappedBlock == 0 {
+			offsets = binary.BigEndian.AppendUint64(offsets, off)
+		}
+		i++
+		n := len(buf)
+		buf = binary.AppendUvarint(buf, uint64(len(key)))
+		buf = append(buf, key...)
+		off += uint64(len(buf) - n)
+		return true
+	})
+	binary.BigEndian.PutUint64(hdr[20:], off)
+	bw := bufio.NewWriter(w)
+	bw.Write(hdr)
+	bw.Write(buf)
+	bw.Write(offsets)
+	return bw.Flush()
+}
+
+func newMappedSet(data []byte, unmap func() error) (*MappedSet, error) {
+	if len(data) < 28 || string(data[:4]) != mappedMagic {
+		return nil, ErrCorrupted
+	}
+	m := &MappedSet{data: data, unmap: unmap}
+	m.count = int(binary.BigEndian.Uint64(data[4:]))
+	blocks := binary.BigEndian.Uint64(data[12:])
+	index := binary.BigEndian.Uint64(data[20:])
+	if blocks > uint64(len(data))/8 || index < 28 || index > uint64(len(data)) ||
+		uint64(len(data))-index != blocks*8 {
+		return nil, ErrCorrupted
+	}
+	m.blocks = int(blocks)
+	m.index = data[index:]
+	// blocks must be non empty, ordered and between header and index
+	prev := uint64(28)
+	for b := 0; b < m.blocks; b++ {
+		off := binary.BigEndian.Uint64(m.index[b*8:])
+		if (b == 0 && off != 28) || (b > 0 && off <= prev) || off >= index {
+			return nil, ErrCorrupted
+		}
+		prev = off
+	}
+	return m, nil
+}
+
+// Len returns the number of keys
+func (m *MappedSet) Len() int {
+	return m.count
+}
+
+// Close unmap file
+func (m *MappedSet) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	err := m.unmap()
+	m.data, m.index, m.unmap = nil, nil, nil
+	return err
+}
+
+func (m *MappedSet) blockStart(b int) int {
+	return int(binary.BigEndian.Uint64(m.index[b*8:]))
+}
+
+func (m *MappedSet) blockEnd(b int) int {
+	if b+1 < m.blocks {
+		return m.blockStart(b + 1)
+	}
+	return len(m.data) - len(m.index)
+}
+
+// readKey read key at off in block ending at end
+// ok is false if key is corrupted and don't fit in block
+func (m *MappedSet) readKey(off, end int) (key []byte, next int, ok bool) {
+	size, n := binary.Uvarint(m.data[off:end])
+	if n <= 0 || size > uint64(end-off-n) {
+		return nil, end, false
+	}
+	off += n
+	return m.data[off : off+int(size) : off+int(size)], off + int(size), true
+}
+
+// blockKeys decode all keys of block
+func (m *MappedSet) blockKeys(b int) (keys [][]byte) {
+	for off, end := m.blockStart(b), m.blockEnd(b); off < end; {
+		key, next, ok := m.readKey(off, end)
+		if !ok {
+			break
+		}
+		keys = append(keys, key)
+		off = next
+	}
+	return
+}
+
+// seek return last block with first key <= key, or 0
+func (m *MappedSet) seek(key []byte) int {
+	b := sort.Search(m.blocks, func(i int) bool {
+		first, _, _ := m.readKey(m.blockStart(i), m.blockEnd(i))
+		return bytes.Compare(first, key) > 0
+	})
+	if b > 0 {
+		b--
+	}
+	return b
+}
+
+// Has return true if key exists
+func (m *MappedSet) Has(key []byte) bool {
+	if m.blocks == 0 {
+		return false
+	}
+	b := m.seek(key)
+	for off, end := m.blockStart(b), m.blockEnd(b); off < end; {
+		k, next, ok := m.readKey(off, end)
+		if !ok {
+			return false
+		}
+		off = next
+		if c := bytes.Compare(k, key); c >= 0 {
+			return c == 0
+		}
+	}
+	return false
+}
+
+// First return first key
+func (m *MappedSet) First() []byte {
+	if m.blocks == 0 {
+		return nil
+	}
+	key, _, _ := m.readKey(m.blockStart(0), m.blockEnd(0))
+	return key
+}
+
+// Last return last key
+func (m *MappedSet) Last() []byte {
+	if m.blocks == 0 {
+		return nil
+	}
+	keys := m.blockKeys(m.blocks - 1)
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[len(keys)-1]
+}
+
+// Ascend the set within the range [pivot, last]
+func (m *MappedSet) Ascend(pivot []byte, iter func(key []byte) bool) {
+	if m.blocks == 0 {
+		return
+	}
+	for b := m.seek(pivot); b < m.blocks; b++ {
+		for off, end := m.blockStart(b), m.blockEnd(b); off < end; {
+			key, next, ok := m.readKey(off, end)
+			if !ok {
+				break
+			}
+			off = next
+			if bytes.Compare(key, pivot) < 0 {
+				continue
+			}
+			if !iter(key) {
+				return
+			}
+		}
+	}
+}
+
+// AscendPrefix ascend the set within keys with prefix
+func (m *MappedSet) AscendPrefix(prefix []byte, iter func(key []byte) bool) {
+	m.Ascend(prefix, func(key []byte) bool {
+		if !bytes.HasPrefix(key, prefix) {
+			return false
+		}
+		return iter(key)
+	})
+}
+
+// Descend the set within the range [pivot, first]
+func (m *MappedSet) Descend(pivot []byte, iter func(key []byte) bool) {
+	if m.blocks == 0 {
+		return
+	}
+	for b := m.seek(pivot); b >= 0; b-- {
+		keys := m.blockKeys(b)
+		for i := len(keys) - 1; i >= 0; i-- {
+			if bytes.Compare(keys[i], pivot) > 0 {
+				continue
+			}
+			if !iter(keys[i]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package btreeset
+
+import "os"
+
+// OpenMapped read file written by WriteMapped, mmap is not used on this platform
+func OpenMapped(path string) (*MappedSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMappedSet(data, nil)
+}
@@ -0,0 +1,105 @@
+package btreeset
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeMappedFile(t *testing.T, tr *BTreeSet) string {
+	path := filepath.Join(t.TempDir(), "set.map")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	assert.NoError(t, WriteMapped(f, tr))
+	assert.NoError(t, f.Close())
+	return path
+}
+
+func TestMappedSet(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 1000; i += 2 {
+		tr.Set([]byte(fmt.Sprintf("key:%04d", i)))
+	}
+	tr.Set([]byte("item:1"))
+	tr.Set([]byte("item:2"))
+	m, err := OpenMapped(writeMappedFile(t, &tr))
+	assert.NoError(t, err)
+	defer m.Close()
+
+	assert.Equal(t, tr.Len(), m.Len())
+	assert.Equal(t, tr.First(), m.First())
+	assert.Equal(t, tr.Last(), m.Last())
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("key:%04d", i))
+		assert.Equal(t, tr.Has(key), m.Has(key))
+	}
+	assert.Equal(t, false, m.Has([]byte("a")))
+	assert.Equal(t, false, m.Has([]byte("z")))
+
+	collect := func(walk func(pivot []byte, iter func(key []byte) bool), pivot []byte) (keys []string) {
+		walk(pivot, func(key []byte) bool {
+			keys = append(keys, string(key))
+			return true
+		})
+		return
+	}
+	for _, pivot := range []string{"", "a", "item:1", "key:0063", "key:0064", "key:0500", "z"} {
+		assert.Equal(t, collect(tr.Ascend, []byte(pivot)), collect(m.Ascend, []byte(pivot)), pivot)
+		assert.Equal(t, collect(tr.Descend, []byte(pivot)), collect(m.Descend, []byte(pivot)), pivot)
+	}
+	assert.Equal(t, []string{"item:1", "item:2"}, collect(m.AscendPrefix, []byte("item")))
+}
+
+func TestMappedSetEmpty(t *testing.T) {
+	var tr BTreeSet
+	m, err := OpenMapped(writeMappedFile(t, &tr))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, m.Len())
+	assert.Equal(t, false, m.Has(nil))
+	assert.Nil(t, m.First())
+	m.Ascend(nil, func(key []byte) bool {
+		t.Fatal("should not be reached")
+		return true
+	})
+	assert.NoError(t, m.Close())
+}
+
+func TestMappedSetCorrupted(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 300; i++ {
+		tr.Set([]byte(fmt.Sprintf("key%03d", i)))
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, WriteMapped(&buf, &tr))
+	data := buf.Bytes()
+
+	// truncated file is rejected at open
+	for n := 0; n < len(data); n++ {
+		_, err := newMappedSet(data[:n], nil)
+		assert.Equal(t, ErrCorrupted, err, n)
+	}
+	// corrupted keys don't panic or loop
+	for i := 28; i < len(data); i++ {
+		bad := append([]byte(nil), data...)
+		bad[i] = 0xff
+		m, err := newMappedSet(bad, nil)
+		if err != nil {
+			continue
+		}
+		m.Has([]byte("key150"))
+		m.First()
+		m.Last()
+		m.Ascend(nil, func(key []byte) bool { return true })
+		m.Descend([]byte("key999"), func(key []byte) bool { return true })
+	}
+	// overlong uvarint in first key
+	bad := append([]byte(nil), data...)
+	copy(bad[28:], bytes.Repeat([]byte{0xff}, 11))
+	m, err := newMappedSet(bad, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, false, m.Has([]byte("key000")))
+}
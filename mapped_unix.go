@@ -0,0 +1,34 @@
+//go:build unix
+
+package btreeset
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenMapped map file written by WriteMapped
+func OpenMapped(path string) (*MappedSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() == 0 {
+		return nil, ErrCorrupted
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(st.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMappedSet(data, func() error { return syscall.Munmap(data) })
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,222 @@
+package btreeset
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBadPattern returned by Match for malformed glob pattern
+var ErrBadPattern = errors.New("btreeset: bad pattern")
+
+// Match iterate keys matching glob pattern in ascending order
+// '*' match any sequence, '?' match any character, '[abc]', '[a-z]'
+// and '[!a-z]' match character class, '\' escape next character
+// Only keys with literal prefix of pattern are visited and key ranges
+// which can't match are skipped, but pattern starting with '*' may
+// match any key prefix, so all keys are checked
+func (tr *BTreeSet) Match(pattern string, iter func(key []byte) bool) error {
+	expr, err := globToRegexp(pattern)
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ErrBadPattern
+	}
+	tr.MatchRegexp(re, iter)
+	return nil
+}
+
+// MatchRegexp iterate keys matching re in ascending order
+// If re is anchored with ^ only keys with its literal prefix are visited,
+// and key ranges which can't match are skipped: after key "user:2x"
+// failed "^user:[01]" the walk seek to "user:3". Unanchored re may
+// match anywhere in key, so all keys are checked
+func (tr *BTreeSet) MatchRegexp(re *regexp.Regexp, iter func(key []byte) bool) {
+	prefix := regexpPrefix(re.String())
+	prune := newPruner(re.String())
+	pivot := prefix
+	for {
+		var seek []byte
+		stop := false
+		tr.Ascend(pivot, func(key []byte) bool {
+			if len(prefix) > 0 && !bytes.HasPrefix(key, prefix) {
+				stop = true
+				return false
+			}
+			if re.Match(key) {
+				stop = !iter(key)
+				return !stop
+			}
+			if prune == nil {
+				return true
+			}
+			next, ok := prune.next(key)
+			if !ok {
+				return true
+			}
+			seek, stop = next, next == nil
+			return false
+		})
+		if stop || seek == nil {
+			return
+		}
+		pivot = seek
+	}
+}
+
+// pruner find key ranges which can't match anchored regexp
+type pruner struct {
+	prog    *syntax.Prog
+	visited []bool
+}
+
+// newPruner return nil if expr is not anchored at start
+func newPruner(expr string) *pruner {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat || re.Sub[0].Op != syntax.OpBeginText {
+		return nil
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil
+	}
+	return &pruner{prog: prog, visited: make([]bool, len(prog.Inst))}
+}
+
+// add pc and its empty transitions to states
+// Empty width assertions are taken as satisfied, so result is
+// a superset of real states
+func (p *pruner) add(states []uint32, pc uint32) []uint32 {
+	if p.visited[pc] {
+		return states
+	}
+	p.visited[pc] = true
+	switch inst := &p.prog.Inst[pc]; inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		states = p.add(states, inst.Out)
+		states = p.add(states, inst.Arg)
+	case syntax.InstCapture, syntax.InstNop, syntax.InstEmptyWidth:
+		states = p.add(states, inst.Out)
+	case syntax.InstMatch, syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+		states = append(states, pc)
+	}
+	return states
+}
+
+func (p *pruner) closure(pcs []uint32) (states []uint32) {
+	for i := range p.visited {
+		p.visited[i] = false
+	}
+	for _, pc := range pcs {
+		states = p.add(states, pc)
+	}
+	return
+}
+
+func matchRune(inst *syntax.Inst, r rune) bool {
+	switch inst.Op {
+	case syntax.InstRune:
+		return inst.MatchRune(r)
+	case syntax.InstRune1:
+		return r == inst.Rune[0]
+	case syntax.InstRuneAny:
+		return true
+	case syntax.InstRuneAnyNotNL:
+		return r != '\n'
+	}
+	return false
+}
+
+// next return key to seek to when no key with some prefix of key can
+// match, nil seek mean no following key can match. ok is false when
+// every prefix of key may still be continued to a match
+func (p *pruner) next(key []byte) (seek []byte, ok bool) {
+	states := p.closure([]uint32{uint32(p.prog.Start)})
+	for off := 0; off < len(key); {
+		r, size := utf8.DecodeRune(key[off:])
+		var next []uint32
+		for _, pc := range states {
+			if inst := &p.prog.Inst[pc]; matchRune(inst, r) {
+				next = append(next, inst.Out)
+			}
+		}
+		if len(next) == 0 {
+			if r == utf8.RuneError && size == 1 {
+				// invalid byte, longer keys with it may decode to other rune
+				return nil, false
+			}
+			return prefixEnd(key[:off+size]), true
+		}
+		states = p.closure(next)
+		off += size
+	}
+	return nil, false
+}
+
+// prefixEnd return first key after all keys with prefix, nil if none
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// regexpPrefix return literal prefix of all keys matching anchored expr
+func regexpPrefix(expr string) []byte {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat || re.Sub[0].Op != syntax.OpBeginText {
+		return nil
+	}
+	var prefix []byte
+	for _, sub := range re.Sub[1:] {
+		if sub.Op != syntax.OpLiteral || sub.Flags&syntax.FoldCase != 0 {
+			break
+		}
+		prefix = append(prefix, string(sub.Rune)...)
+	}
+	return prefix
+}
+
+// globToRegexp translate glob pattern to anchored regexp
+func globToRegexp(pattern string) (string, error) {
+	var b strings.Builder
+	b.WriteString(`(?s)^`)
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return "", ErrBadPattern
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", ErrBadPattern
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString(`$`)
+	return b.String(), nil
+}
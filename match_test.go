@@ -0,0 +1,104 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 20; i++ {
+		tr.Set([]byte(fmt.Sprintf("user:%02d:session", i)))
+		tr.Set([]byte(fmt.Sprintf("user:%02d:profile", i)))
+		tr.Set([]byte(fmt.Sprintf("group:%02d:session", i)))
+	}
+	match := func(pattern string) (keys []string) {
+		assert.NoError(t, tr.Match(pattern, func(key []byte) bool {
+			keys = append(keys, string(key))
+			return true
+		}))
+		return
+	}
+	assert.Equal(t, 20, len(match("user:*:session")))
+	assert.Equal(t, 40, len(match("*:session")))
+	assert.Equal(t, []string{"user:01:profile", "user:11:profile"}, match("user:?1:profile"))
+	assert.Equal(t, []string{"group:03:session", "group:13:session"}, match("group:[01]3:*"))
+	assert.Equal(t, 10, len(match("user:[!1]?:profile")))
+	assert.Equal(t, []string{"user:05:session"}, match("user:05:session"))
+	assert.Equal(t, 0, len(match(`user\*`)))
+	assert.Equal(t, 60, len(match("*")))
+
+	err := tr.Match("user:[0", func(key []byte) bool { return true })
+	assert.Equal(t, ErrBadPattern, err)
+	err = tr.Match(`user\`, func(key []byte) bool { return true })
+	assert.Equal(t, ErrBadPattern, err)
+
+	n := 0
+	tr.MatchRegexp(regexp.MustCompile(`^user:1\d:(session|profile)$`), func(key []byte) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 20, n)
+	n = 0
+	tr.MatchRegexp(regexp.MustCompile(`0[0-4]:session`), func(key []byte) bool {
+		n++
+		return n < 3
+	})
+	assert.Equal(t, 3, n)
+}
+
+func TestRegexpPrefix(t *testing.T) {
+	assert.Equal(t, []byte("user:"), regexpPrefix(`(?s)^user:.*:session$`))
+	assert.Equal(t, []byte("ab"), regexpPrefix(`^ab[cd]`))
+	assert.Nil(t, regexpPrefix(`user:`))
+	assert.Nil(t, regexpPrefix(`^(?i)user`))
+	assert.Nil(t, regexpPrefix(`(?m)^user`))
+}
+
+func TestMatchPrune(t *testing.T) {
+	p := newPruner(`(?s)^user:[01].*$`)
+	seek, ok := p.next([]byte("user:2abc"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []byte("user:3"), seek)
+	_, ok = p.next([]byte("user:1abc"))
+	assert.Equal(t, false, ok)
+	seek, ok = p.next([]byte("v"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, []byte("w"), seek)
+	// invalid byte may start other rune in longer key
+	_, ok = p.next([]byte("user:\xe2"))
+	assert.Equal(t, false, ok)
+	assert.Nil(t, prefixEnd([]byte("\xff\xff")))
+	assert.Equal(t, []byte("b"), prefixEnd([]byte("a\xff")))
+	assert.Nil(t, newPruner(`user:[01]`))
+
+	// pruned walk return same keys as full scan
+	var tr BTreeSet
+	alphabet := []string{"a", "b", ":", "é", "\xff", "\xc3", "0", "1"}
+	for i := 0; i < 5000; i++ {
+		var key string
+		for j := rand.Intn(8); j > 0; j-- {
+			key += alphabet[rand.Intn(len(alphabet))]
+		}
+		tr.Set([]byte(key))
+	}
+	for _, expr := range []string{`^a[b:]`, `^[ab]*:1`, `(?s)^a.é`, `^(ab|é)+$`, `^.[01]`, `^\x{e9}{2}`, `a:b`, `^$`} {
+		re := regexp.MustCompile(expr)
+		var exp, got []string
+		tr.Scan(func(key []byte) bool {
+			if re.Match(key) {
+				exp = append(exp, string(key))
+			}
+			return true
+		})
+		tr.MatchRegexp(re, func(key []byte) bool {
+			got = append(got, string(key))
+			return true
+		})
+		assert.Equal(t, exp, got, expr)
+	}
+}
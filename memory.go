@@ -0,0 +1,88 @@
+package btreeset
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// ErrMemoryLimit returned by TrySet when set would grow past memory limit
+var ErrMemoryLimit = errors.New("btreeset: memory limit exceeded")
+
+const (
+	nodeSize    = int(unsafe.Sizeof(node{}))
+	pointerSize = int(unsafe.Sizeof((*node)(nil)))
+)
+
+// MemStats is memory used by tree
+// Nodes shared with copies of tree are counted by every copy
+type MemStats struct {
+	// Nodes is number of nodes
+	Nodes int
+	// NodeBytes is size of nodes: items, children pointers and counters
+	NodeBytes int
+	// Pointers is number of used children pointers, part of NodeBytes
+	Pointers     int
+	PointerBytes int
+	// KeyBytes is total length of keys
+	KeyBytes int
+	// Total is NodeBytes + KeyBytes
+	Total int
+}
+
+// MemoryUsage return memory used by tree
+func (tr *BTreeSet) MemoryUsage() MemStats {
+	m := MemStats{Nodes: tr.nodes, KeyBytes: tr.keyBytes}
+	if m.Nodes > 0 {
+		// every node but root is a child of other node
+		m.Pointers = m.Nodes - 1
+	}
+	m.NodeBytes = m.Nodes * nodeSize
+	m.PointerBytes = m.Pointers * pointerSize
+	m.Total = m.NodeBytes + m.KeyBytes
+	return m
+}
+
+// SetMemoryLimit set max Total bytes of MemoryUsage for TrySet, 0 - no limit
+// The limit is checked only by TrySet. Set, Batch, Txn and wrappers
+// keep Set signature without error and ignore the limit, so writers
+// which must respect the budget have to use TrySet
+func (tr *BTreeSet) SetMemoryLimit(limit int) {
+	tr.memLimit = limit
+}
+
+// growth return number of nodes added by insert of new key
+func (tr *BTreeSet) growth(key []byte) (added int) {
+	if tr.root == nil {
+		return 1
+	}
+	var path []*node
+	n := tr.root
+	for height := tr.height; ; height-- {
+		path = append(path, n)
+		if height == 0 {
+			break
+		}
+		i, _ := n.find(key)
+		n = n.children[i]
+	}
+	// full nodes split from leaf up, split of root add new root
+	for j := len(path) - 1; j >= 0 && path[j].numItems == maxItems-1; j-- {
+		added++
+		if j == 0 {
+			added++
+		}
+	}
+	return
+}
+
+// TrySet set a key, or return ErrMemoryLimit and leave tree unchanged
+// if tree would grow past memory limit. Set ignore the limit
+func (tr *BTreeSet) TrySet(key []byte) (replaced bool, err error) {
+	if tr.memLimit > 0 && !tr.Has(key) {
+		total := tr.keyBytes + len(key) + (tr.nodes+tr.growth(key))*nodeSize
+		if total > tr.memLimit {
+			return false, ErrMemoryLimit
+		}
+	}
+	return tr.Set(key), nil
+}
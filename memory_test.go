@@ -0,0 +1,68 @@
+package btreeset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countNodes walk tree, to check incremental counters
+func (n *node) countNodes(height int) int {
+	if n == nil {
+		return 0
+	}
+	count := 1
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			count += n.children[i].countNodes(height - 1)
+		}
+	}
+	return count
+}
+
+func TestMemoryUsage(t *testing.T) {
+	var tr BTreeSet
+	assert.Equal(t, MemStats{}, tr.MemoryUsage())
+	keys := randKeys(20000)
+	for _, k := range keys {
+		tr.Set([]byte(k))
+	}
+	m := tr.MemoryUsage()
+	assert.Equal(t, tr.root.countNodes(tr.height), m.Nodes)
+	assert.Equal(t, 20000*5, m.KeyBytes)
+	assert.Equal(t, m.Nodes-1, m.Pointers)
+	assert.Equal(t, m.Nodes*nodeSize+m.KeyBytes, m.Total)
+	for _, k := range keys[:15000] {
+		tr.Delete([]byte(k))
+	}
+	m = tr.MemoryUsage()
+	assert.Equal(t, tr.root.countNodes(tr.height), m.Nodes)
+	assert.Equal(t, 5000*5, m.KeyBytes)
+	for _, k := range keys[15000:] {
+		tr.Delete([]byte(k))
+	}
+	assert.Equal(t, MemStats{}, tr.MemoryUsage())
+}
+
+func TestMemoryLimit(t *testing.T) {
+	var tr BTreeSet
+	tr.SetMemoryLimit(3 * nodeSize)
+	var err error
+	var n int
+	for ; err == nil; n++ {
+		_, err = tr.TrySet([]byte(fmt.Sprintf("%06d", n)))
+	}
+	assert.Equal(t, ErrMemoryLimit, err)
+	n--
+	assert.Equal(t, n, tr.Len())
+	assert.Equal(t, true, tr.MemoryUsage().Total <= 3*nodeSize)
+	assert.Equal(t, false, tr.Has([]byte(fmt.Sprintf("%06d", n))))
+	// existing key is accepted
+	replaced, err := tr.TrySet([]byte("000000"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, replaced)
+	// Set ignore limit
+	tr.Set([]byte(fmt.Sprintf("%06d", n)))
+	assert.Equal(t, n+1, tr.Len())
+}
@@ -0,0 +1,174 @@
+package btreeset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+)
+
+// keyHash is a fast 64-bit hash of key, stable between processes
+func keyHash(key []byte) uint64 {
+	const (
+		p1 = 0x9e3779b97f4a7c15
+		p2 = 0xbf58476d1ce4e5b9
+	)
+	h := uint64(len(key)) * p1
+	for ; len(key) >= 8; key = key[8:] {
+		h = bits.RotateLeft64(h^binary.LittleEndian.Uint64(key)*p2, 31) * p1
+	}
+	var tail [8]byte
+	copy(tail[:], key)
+	h = bits.RotateLeft64(h^binary.LittleEndian.Uint64(tail[:])*p2, 31) * p1
+	// finalizer of splitmix64
+	h ^= h >> 30
+	h *= p2
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+func nodeHash(n *node) uint64 {
+	if n.aug == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(n.aug)
+}
+
+// merkleHash store in node xor of key hashes of subtree, so hash
+// of range don't depend on shape of tree and equal on replicas
+func merkleHash(n *node, height int) {
+	var h uint64
+	for i := 0; i < n.numItems; i++ {
+		h ^= keyHash(n.items[i].key)
+	}
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			h ^= nodeHash(n.children[i])
+		}
+	}
+	// new slice, old one may be shared with copy of node
+	n.aug = make([]byte, 8)
+	binary.BigEndian.PutUint64(n.aug, h)
+}
+
+// EnableHash maintain hash of keys in every node, needed for fast RangeHash
+func (tr *BTreeSet) EnableHash() {
+	if tr.hashed {
+		return
+	}
+	tr.hashed = true
+	tr.augment = merkleHash
+	if tr.root != nil {
+		tr.root = tr.root.rehash(tr.height, tr.cow)
+	}
+}
+
+// rehash subtree, nodes shared with copies of tree are cloned
+func (n *node) rehash(height int, c *cow) *node {
+	n = n.mut(c)
+	if height > 0 {
+		for i := 0; i <= n.numItems; i++ {
+			n.children[i] = n.children[i].rehash(height-1, c)
+		}
+	}
+	merkleHash(n, height)
+	return n
+}
+
+// RangeHash return hash of keys within the range [lo, hi), nil hi is end of set
+// Equal sets have equal hashes. Without EnableHash all keys in range are hashed
+func (tr *BTreeSet) RangeHash(lo, hi []byte) (h uint64) {
+	if tr.root == nil {
+		return 0
+	}
+	if !tr.hashed {
+		tr.Ascend(lo, func(key []byte) bool {
+			if hi != nil && bytes.Compare(key, hi) >= 0 {
+				return false
+			}
+			h ^= keyHash(key)
+			return true
+		})
+		return
+	}
+	return tr.root.rangeHash(lo, hi, tr.height, lo == nil, hi == nil)
+}
+
+// rangeHash of subtree, loIn and hiIn are true if all keys of subtree are >= lo or < hi
+func (n *node) rangeHash(lo, hi []byte, height int, loIn, hiIn bool) (h uint64) {
+	if loIn && hiIn {
+		return nodeHash(n)
+	}
+	for i := 0; i <= n.numItems; i++ {
+		if height > 0 {
+			// child i is between items i-1 and i
+			below := i < n.numItems && !loIn && bytes.Compare(n.items[i].key, lo) <= 0
+			above := i > 0 && !hiIn && bytes.Compare(n.items[i-1].key, hi) >= 0
+			if !below && !above {
+				childLo := loIn || (i > 0 && bytes.Compare(n.items[i-1].key, lo) >= 0)
+				childHi := hiIn || (i < n.numItems && bytes.Compare(n.items[i].key, hi) <= 0)
+				h ^= n.children[i].rangeHash(lo, hi, height-1, childLo, childHi)
+			}
+		}
+		if i < n.numItems {
+			key := n.items[i].key
+			if (loIn || bytes.Compare(key, lo) >= 0) && (hiIn || bytes.Compare(key, hi) < 0) {
+				h ^= keyHash(key)
+			}
+		}
+	}
+	return
+}
+
+// separators return keys of highest node with keys within the range (lo, hi)
+func (tr *BTreeSet) separators(lo, hi []byte) (keys [][]byte) {
+	n := tr.root
+	for height := tr.height; n != nil; height-- {
+		// first key > lo
+		i, found := n.find(lo)
+		if found {
+			i++
+		}
+		for j := i; j < n.numItems; j++ {
+			key := n.items[j].key
+			if hi != nil && bytes.Compare(key, hi) >= 0 {
+				break
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) > 0 || height == 0 {
+			return
+		}
+		// whole range is inside one child
+		n = n.children[i]
+	}
+	return
+}
+
+// DiffRanges compare range hashes of a and b, splitting differing
+// ranges by separator keys, and call fn for every smallest differing
+// range [lo, hi), nil hi is end of set
+func DiffRanges(a, b *BTreeSet, fn func(lo, hi []byte) bool) {
+	diffRanges(a, b, nil, nil, fn)
+}
+
+func diffRanges(a, b *BTreeSet, lo, hi []byte, fn func(lo, hi []byte) bool) bool {
+	if a.RangeHash(lo, hi) == b.RangeHash(lo, hi) {
+		return true
+	}
+	seps := a.separators(lo, hi)
+	if len(seps) == 0 {
+		seps = b.separators(lo, hi)
+	}
+	if len(seps) == 0 {
+		return fn(lo, hi)
+	}
+	start := lo
+	for _, sep := range seps {
+		if !diffRanges(a, b, start, sep, fn) {
+			return false
+		}
+		start = sep
+	}
+	return diffRanges(a, b, start, hi, fn)
+}
@@ -0,0 +1,107 @@
+package btreeset
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeHash(t *testing.T) {
+	var a, b BTreeSet
+	a.EnableHash()
+	keys := randKeys(20000)
+	for _, k := range keys {
+		a.Set([]byte(k))
+	}
+	// other insert order and late EnableHash give other shape, same hashes
+	for i := len(keys) - 1; i >= 0; i-- {
+		b.Set([]byte(keys[i]))
+	}
+	b.EnableHash()
+	for _, k := range keys[:5000] {
+		a.Delete([]byte(k))
+		b.Delete([]byte(k))
+	}
+	var plain BTreeSet
+	for _, k := range keys[5000:] {
+		plain.Set([]byte(k))
+	}
+	assert.Equal(t, a.RangeHash(nil, nil), b.RangeHash(nil, nil))
+	assert.NotEqual(t, uint64(0), a.RangeHash(nil, nil))
+	for i := 0; i < 200; i++ {
+		lo := []byte(fmt.Sprintf("%05d", rand.Intn(21000)))
+		hi := []byte(fmt.Sprintf("%05d", rand.Intn(21000)))
+		if bytes.Compare(lo, hi) > 0 {
+			lo, hi = hi, lo
+		}
+		exp := plain.RangeHash(lo, hi)
+		assert.Equal(t, exp, a.RangeHash(lo, hi))
+		assert.Equal(t, exp, b.RangeHash(lo, hi))
+	}
+	b.Set([]byte("x"))
+	assert.NotEqual(t, a.RangeHash(nil, nil), b.RangeHash(nil, nil))
+	assert.Equal(t, a.RangeHash(nil, []byte("x")), b.RangeHash(nil, []byte("x")))
+}
+
+func TestDiffRanges(t *testing.T) {
+	var a, b BTreeSet
+	a.EnableHash()
+	b.EnableHash()
+	for _, k := range randKeys(50000) {
+		a.Set([]byte(k))
+		b.Set([]byte(k))
+	}
+	DiffRanges(&a, &b, func(lo, hi []byte) bool {
+		t.Fatal("sets are equal")
+		return true
+	})
+	a.Delete([]byte("12345"))
+	b.Set([]byte("40000a"))
+	var ranges [][2][]byte
+	DiffRanges(&a, &b, func(lo, hi []byte) bool {
+		ranges = append(ranges, [2][]byte{lo, hi})
+		return true
+	})
+	assert.Equal(t, 2, len(ranges))
+	inRange := func(key string, r [2][]byte) bool {
+		return bytes.Compare([]byte(key), r[0]) >= 0 && (r[1] == nil || bytes.Compare([]byte(key), r[1]) < 0)
+	}
+	assert.Equal(t, true, inRange("12345", ranges[0]))
+	assert.Equal(t, true, inRange("40000a", ranges[1]))
+	// ranges are narrowed to leaf keys
+	var n int
+	b.Ascend(ranges[0][0], func(key []byte) bool {
+		if !inRange(string(key), ranges[0]) {
+			return false
+		}
+		n++
+		return true
+	})
+	assert.Equal(t, true, n < maxItems)
+}
+
+func TestEnableHashCopy(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 10000; i++ {
+		tr.Set([]byte(fmt.Sprint(i)))
+	}
+	cp := tr.Copy()
+	tr.EnableHash()
+	// nodes shared with copy are cloned, not hashed in place
+	assert.True(t, tr.root != cp.root)
+	assert.Nil(t, cp.root.aug)
+	assert.Nil(t, cp.root.children[0].aug)
+	assert.NoError(t, tr.Validate())
+	assert.NoError(t, cp.Validate())
+	assert.Equal(t, cp.RangeHash(nil, nil), tr.RangeHash(nil, nil))
+
+	// without copies nodes are hashed in place
+	var own BTreeSet
+	own.Set([]byte("a"))
+	root := own.root
+	own.EnableHash()
+	assert.True(t, root == own.root)
+}
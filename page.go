@@ -0,0 +1,95 @@
+package btreeset
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrInvalidCursor returned by DecodeCursor for malformed or forged token
+var ErrInvalidCursor = errors.New("btreeset: invalid cursor")
+
+// Page return up to limit keys after cursor, in descending order if reverse
+// nil cursor start from first (or last) key. next is cursor for the
+// following page, nil if there are no more keys
+// Cursor is the last returned key, so keys inserted or deleted between
+// pages don't shift the pages
+func (tr *BTreeSet) Page(cursor []byte, limit int, reverse bool) (keys [][]byte, next []byte) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	iter := func(key []byte) bool {
+		if cursor != nil && bytes.Equal(key, cursor) {
+			return true
+		}
+		if len(keys) == limit {
+			next = keys[len(keys)-1]
+			return false
+		}
+		keys = append(keys, key)
+		return true
+	}
+	switch {
+	case cursor == nil && reverse:
+		tr.Reverse(iter)
+	case cursor == nil:
+		tr.Scan(iter)
+	case reverse:
+		tr.Descend(cursor, iter)
+	default:
+		tr.Ascend(cursor, iter)
+	}
+	return
+}
+
+// cursorVersion is first byte of cursor token, so empty key cursor
+// is not empty token
+const cursorVersion = 1
+
+// EncodeCursor return url safe token for cursor, nil cursor is ""
+// If secret is not nil token is signed with HMAC-SHA256
+func EncodeCursor(cursor []byte, secret []byte) string {
+	if cursor == nil {
+		return ""
+	}
+	buf := append([]byte{cursorVersion}, cursor...)
+	if secret != nil {
+		buf = cursorMAC(buf, secret)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// DecodeCursor return cursor of token made by EncodeCursor with same secret
+// Empty token is nil cursor
+func DecodeCursor(token string, secret []byte) ([]byte, error) {
+	if token == "" {
+		return nil, nil
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if secret != nil {
+		if len(buf) < sha256.Size {
+			return nil, ErrInvalidCursor
+		}
+		body := buf[:len(buf)-sha256.Size]
+		if !hmac.Equal(cursorMAC(body, secret)[len(body):], buf[len(body):]) {
+			return nil, ErrInvalidCursor
+		}
+		buf = body
+	}
+	if len(buf) == 0 || buf[0] != cursorVersion {
+		return nil, ErrInvalidCursor
+	}
+	return buf[1:], nil
+}
+
+// cursorMAC append HMAC of buf to buf
+func cursorMAC(buf []byte, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(buf)
+	return mac.Sum(buf[:len(buf):len(buf)])
+}
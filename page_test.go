@@ -0,0 +1,103 @@
+package btreeset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPage(t *testing.T) {
+	var tr BTreeSet
+	keys, next := tr.Page(nil, 10, false)
+	assert.Equal(t, 0, len(keys))
+	assert.Nil(t, next)
+
+	for i := 0; i < 100; i += 2 {
+		tr.Set([]byte(fmt.Sprintf("%03d", i)))
+	}
+	for _, reverse := range []bool{false, true} {
+		var all []string
+		var cursor []byte
+		pages := 0
+		for {
+			keys, next := tr.Page(cursor, 10, reverse)
+			pages++
+			for _, key := range keys {
+				all = append(all, string(key))
+			}
+			// insert before and after cursor between pages
+			if pages == 2 {
+				tr.Set([]byte("001"))
+				tr.Set([]byte("099"))
+			}
+			if next == nil {
+				break
+			}
+			cursor = next
+		}
+		// key inserted ahead of cursor is returned, behind is not
+		assert.Equal(t, 6, pages)
+		assert.Equal(t, 51, len(all))
+		for i := 1; i < len(all); i++ {
+			assert.Equal(t, reverse, all[i] < all[i-1])
+		}
+		tr.Delete([]byte("001"))
+		tr.Delete([]byte("099"))
+	}
+
+	// cursor key deleted between pages
+	keys, next = tr.Page(nil, 3, false)
+	assert.Equal(t, []byte("004"), next)
+	tr.Delete(next)
+	keys, _ = tr.Page(next, 3, false)
+	assert.Equal(t, [][]byte{[]byte("006"), []byte("008"), []byte("010")}, keys)
+	tr.Set([]byte("004"))
+
+	keys, next = tr.Page([]byte("005"), 3, true)
+	assert.Equal(t, [][]byte{[]byte("004"), []byte("002"), []byte("000")}, keys)
+	assert.Nil(t, next)
+}
+
+func TestCursorToken(t *testing.T) {
+	cursor := []byte("user:42")
+	c, err := DecodeCursor(EncodeCursor(cursor, nil), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, c)
+
+	c, err = DecodeCursor("", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	secret := []byte("secret")
+	token := EncodeCursor(cursor, secret)
+	c, err = DecodeCursor(token, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, c)
+
+	_, err = DecodeCursor(token, []byte("other"))
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = DecodeCursor(EncodeCursor([]byte("user:43"), nil), secret)
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = DecodeCursor("!!", nil)
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	// empty key is a cursor, not start of pages
+	for _, secret := range [][]byte{nil, secret} {
+		token := EncodeCursor([]byte{}, secret)
+		assert.NotEqual(t, "", token)
+		c, err = DecodeCursor(token, secret)
+		assert.NoError(t, err)
+		assert.True(t, c != nil)
+		assert.Equal(t, 0, len(c))
+	}
+	var tr BTreeSet
+	tr.Set([]byte{})
+	tr.Set([]byte("a"))
+	keys, next := tr.Page(nil, 1, false)
+	assert.Equal(t, [][]byte{{}}, keys)
+	c, _ = DecodeCursor(EncodeCursor(next, nil), nil)
+	keys, next = tr.Page(c, 1, false)
+	assert.Equal(t, [][]byte{[]byte("a")}, keys)
+	assert.Nil(t, next)
+}
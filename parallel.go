@@ -0,0 +1,149 @@
+package btreeset
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// scanTask is a subtree followed by separator item of parent
+// Keys of subtree are within (min, max), nil bound is unbounded
+type scanTask struct {
+	n        *node
+	height   int
+	sep      *item // nil for last child
+	min, max []byte
+}
+
+// scanTasks split tree at node boundaries into at least workers*4
+// tasks, if tree is big enough. Tasks out of range [lo, hi) are dropped
+func (tr *BTreeSet) scanTasks(lo, hi []byte, workers int) []scanTask {
+	if tr.root == nil {
+		return nil
+	}
+	tasks := []scanTask{{n: tr.root, height: tr.height}}
+	for len(tasks) < workers*4 {
+		var next []scanTask
+		split := false
+		for _, t := range tasks {
+			if t.height == 0 {
+				next = append(next, t)
+				continue
+			}
+			split = true
+			min := t.min
+			for i := 0; i <= t.n.numItems; i++ {
+				c := scanTask{n: t.n.children[i], height: t.height - 1, sep: t.sep, min: min, max: t.max}
+				if i < t.n.numItems {
+					c.sep = &t.n.items[i]
+					c.max = c.sep.key
+					min = c.sep.key
+				}
+				if (hi == nil || c.min == nil || bytes.Compare(c.min, hi) < 0) &&
+					(lo == nil || c.max == nil || bytes.Compare(c.max, lo) >= 0) {
+					next = append(next, c)
+				}
+			}
+		}
+		tasks = next
+		if !split {
+			break
+		}
+	}
+	return tasks
+}
+
+// walk keys of task within the range [lo, hi) in order
+func (t *scanTask) walk(lo, hi []byte, iter func(key []byte) bool) {
+	ok := t.n.ascend(lo, func(key []byte) bool {
+		if hi != nil && bytes.Compare(key, hi) >= 0 {
+			return false
+		}
+		return iter(key)
+	}, t.height, false)
+	if ok && t.sep != nil && (hi == nil || bytes.Compare(t.sep.key, hi) < 0) {
+		iter(t.sep.key)
+	}
+}
+
+// parallel run tasks by workers goroutines
+func (tr *BTreeSet) parallel(tasks []scanTask, workers int, run func(i int, t *scanTask)) {
+	if workers < 1 {
+		workers = 1
+	}
+	var next int64 = -1
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(tasks) {
+					return
+				}
+				run(i, &tasks[i])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ParallelScan call fn for all keys from workers goroutines
+// Keys are not ordered, fn must be safe for concurrent use
+// Once fn return false no new calls are made
+// Tree must not be changed while scanning
+func (tr *BTreeSet) ParallelScan(workers int, fn func(key []byte) bool) {
+	tr.ParallelRange(nil, nil, workers, fn)
+}
+
+// ParallelRange call fn for keys within the range [lo, hi) from
+// workers goroutines, nil hi is the end of the set
+// Keys are not ordered, fn must be safe for concurrent use
+// Once fn return false no new calls are made
+// Tree must not be changed while scanning
+func (tr *BTreeSet) ParallelRange(lo, hi []byte, workers int, fn func(key []byte) bool) {
+	var stop int32
+	iter := func(key []byte) bool {
+		if atomic.LoadInt32(&stop) != 0 {
+			return false
+		}
+		if !fn(key) {
+			atomic.StoreInt32(&stop, 1)
+			return false
+		}
+		return true
+	}
+	tasks := tr.scanTasks(lo, hi, workers)
+	tr.parallel(tasks, workers, func(i int, t *scanTask) {
+		t.walk(lo, hi, iter)
+	})
+}
+
+// ParallelReduce split keys into ordered parts and fold every part from
+// init() with fold in key order, parts are folded by workers goroutines
+// Results of parts are combined with merge from first to last part, so
+// fold and merge may depend on key order
+// Tree must not be changed while reducing
+func (tr *BTreeSet) ParallelReduce(workers int, init func() interface{},
+	fold func(acc interface{}, key []byte) interface{},
+	merge func(left, right interface{}) interface{}) interface{} {
+	tasks := tr.scanTasks(nil, nil, workers)
+	results := make([]interface{}, len(tasks))
+	tr.parallel(tasks, workers, func(i int, t *scanTask) {
+		acc := init()
+		t.walk(nil, nil, func(key []byte) bool {
+			acc = fold(acc, key)
+			return true
+		})
+		results[i] = acc
+	})
+	if len(results) == 0 {
+		return init()
+	}
+	acc := results[0]
+	for _, r := range results[1:] {
+		acc = merge(acc, r)
+	}
+	return acc
+}
@@ -0,0 +1,97 @@
+package btreeset
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelScan(t *testing.T) {
+	var tr BTreeSet
+	var mu sync.Mutex
+	var keys []string
+	tr.ParallelScan(4, func(key []byte) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	assert.Equal(t, 0, len(keys))
+
+	for i := 0; i < 100000; i++ {
+		tr.Set([]byte(fmt.Sprintf("%06d", i)))
+	}
+	for _, workers := range []int{1, 3, 8} {
+		keys = keys[:0]
+		tr.ParallelScan(workers, func(key []byte) bool {
+			mu.Lock()
+			keys = append(keys, string(key))
+			mu.Unlock()
+			return true
+		})
+		assert.Equal(t, tr.Len(), len(keys))
+		sort.Strings(keys)
+		for i, key := range keys {
+			if key != fmt.Sprintf("%06d", i) {
+				t.Fatal(workers, i, key)
+			}
+		}
+	}
+
+	var n int64
+	tr.ParallelScan(4, func(key []byte) bool {
+		return atomic.AddInt64(&n, 1) < 10
+	})
+	assert.True(t, n < 1000)
+}
+
+func TestParallelRange(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 100000; i++ {
+		tr.Set([]byte(fmt.Sprintf("%06d", i)))
+	}
+	for _, r := range [][2]string{{"", ""}, {"012345", "012350"}, {"050000", ""}, {"", "000100"}, {"0123456", "099999"}} {
+		lo, hi := []byte(r[0]), []byte(r[1])
+		if r[1] == "" {
+			hi = nil
+		}
+		var exp []string
+		tr.Ascend(lo, func(key []byte) bool {
+			if hi != nil && string(key) >= string(hi) {
+				return false
+			}
+			exp = append(exp, string(key))
+			return true
+		})
+		var mu sync.Mutex
+		var got []string
+		tr.ParallelRange(lo, hi, 4, func(key []byte) bool {
+			mu.Lock()
+			got = append(got, string(key))
+			mu.Unlock()
+			return true
+		})
+		sort.Strings(got)
+		assert.Equal(t, exp, got, r)
+	}
+}
+
+func TestParallelReduce(t *testing.T) {
+	var tr BTreeSet
+	concat := func() interface{} { return "" }
+	fold := func(acc interface{}, key []byte) interface{} {
+		return acc.(string) + string(key)
+	}
+	merge := func(left, right interface{}) interface{} {
+		return left.(string) + right.(string)
+	}
+	assert.Equal(t, "", tr.ParallelReduce(4, concat, fold, merge))
+	exp := ""
+	for i := 0; i < 20000; i++ {
+		tr.Set([]byte(fmt.Sprintf("%05d", i)))
+		exp += fmt.Sprintf("%05d", i)
+	}
+	assert.Equal(t, exp, tr.ParallelReduce(4, concat, fold, merge))
+}
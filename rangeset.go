@@ -0,0 +1,165 @@
+package btreeset
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// RangeSet is a set of uint64 values, stored as maximal runs [start, end]
+// Number of all uint64 values, 2^64, don't fit in uint64, so counts of
+// full range saturate at math.MaxUint64
+type RangeSet struct {
+	tree        BTreeSet
+	cardinality uint64 // modulo 2^64, 0 for full range
+}
+
+// full return true if set hold all uint64 values
+func (s *RangeSet) full() bool {
+	start, end, ok := s.runAt(0)
+	return ok && start == 0 && end == math.MaxUint64
+}
+
+func runKey(start, end uint64) []byte {
+	p := make([]byte, 16)
+	binary.BigEndian.PutUint64(p, start)
+	binary.BigEndian.PutUint64(p[8:], end)
+	return p
+}
+
+func parseRun(key []byte) (start, end uint64) {
+	return binary.BigEndian.Uint64(key), binary.BigEndian.Uint64(key[8:])
+}
+
+// runAt return run with max start <= v
+func (s *RangeSet) runAt(v uint64) (start, end uint64, ok bool) {
+	s.tree.Descend(runKey(v, math.MaxUint64), func(key []byte) bool {
+		start, end = parseRun(key)
+		ok = true
+		return false
+	})
+	return
+}
+
+// runs return runs which overlap [lo, hi], or touch it if adjacent is true
+func (s *RangeSet) runs(lo, hi uint64, adjacent bool) (runs [][2]uint64) {
+	if start, end, ok := s.runAt(lo); ok {
+		if end >= lo || (adjacent && end+1 == lo) {
+			runs = append(runs, [2]uint64{start, end})
+		}
+	}
+	s.tree.Ascend(runKey(lo, math.MaxUint64), func(key []byte) bool {
+		start, end := parseRun(key)
+		if start <= lo {
+			// run (lo, MaxUint64) is at pivot and already found by runAt
+			return true
+		}
+		if start > hi && !(adjacent && start-1 == hi) {
+			return false
+		}
+		runs = append(runs, [2]uint64{start, end})
+		return true
+	})
+	return
+}
+
+func (s *RangeSet) setRun(start, end uint64) {
+	s.tree.Set(runKey(start, end))
+	s.cardinality += end - start + 1
+}
+
+func (s *RangeSet) deleteRun(start, end uint64) {
+	s.tree.Delete(runKey(start, end))
+	s.cardinality -= end - start + 1
+}
+
+// Add value, return false if value exists
+func (s *RangeSet) Add(v uint64) (added bool) {
+	return s.AddRange(v, v) > 0
+}
+
+// AddRange add values within the range [lo, hi], return number of added values
+func (s *RangeSet) AddRange(lo, hi uint64) (added uint64) {
+	if lo > hi {
+		return 0
+	}
+	before, wasFull := s.cardinality, s.full()
+	start, end := lo, hi
+	for _, r := range s.runs(lo, hi, true) {
+		s.deleteRun(r[0], r[1])
+		if r[0] < start {
+			start = r[0]
+		}
+		if r[1] > end {
+			end = r[1]
+		}
+	}
+	s.setRun(start, end)
+	if !wasFull && before == 0 && s.full() {
+		// 2^64 added
+		return math.MaxUint64
+	}
+	return s.cardinality - before
+}
+
+// Remove value, return false if value not exists
+func (s *RangeSet) Remove(v uint64) (removed bool) {
+	return s.RemoveRange(v, v) > 0
+}
+
+// RemoveRange remove values within the range [lo, hi], return number of removed values
+func (s *RangeSet) RemoveRange(lo, hi uint64) (removed uint64) {
+	if lo > hi {
+		return 0
+	}
+	before, wasFull := s.cardinality, s.full()
+	for _, r := range s.runs(lo, hi, false) {
+		s.deleteRun(r[0], r[1])
+		if r[0] < lo {
+			s.setRun(r[0], lo-1)
+		}
+		if r[1] > hi {
+			s.setRun(hi+1, r[1])
+		}
+	}
+	if wasFull && s.cardinality == 0 {
+		// 2^64 removed
+		return math.MaxUint64
+	}
+	return before - s.cardinality
+}
+
+// Contains return true if value exists
+func (s *RangeSet) Contains(v uint64) bool {
+	_, end, ok := s.runAt(v)
+	return ok && end >= v
+}
+
+// Cardinality return number of values, math.MaxUint64 for full range
+func (s *RangeSet) Cardinality() uint64 {
+	if s.cardinality == 0 && s.full() {
+		return math.MaxUint64
+	}
+	return s.cardinality
+}
+
+// Runs iterate all runs [start, end] in ascending order
+func (s *RangeSet) Runs(iter func(start, end uint64) bool) {
+	s.tree.Scan(func(key []byte) bool {
+		return iter(parseRun(key))
+	})
+}
+
+// Scan all values in ascending order
+func (s *RangeSet) Scan(iter func(v uint64) bool) {
+	s.tree.Scan(func(key []byte) bool {
+		start, end := parseRun(key)
+		for v := start; ; v++ {
+			if !iter(v) {
+				return false
+			}
+			if v == end {
+				return true
+			}
+		}
+	})
+}
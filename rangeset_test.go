@@ -0,0 +1,126 @@
+package btreeset
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeSet(t *testing.T) {
+	var s RangeSet
+	for v := uint64(0); v < 1000; v++ {
+		assert.Equal(t, true, s.Add(v))
+	}
+	assert.Equal(t, false, s.Add(500))
+	assert.Equal(t, uint64(1000), s.Cardinality())
+	assert.Equal(t, 1, s.tree.Len())
+
+	assert.Equal(t, true, s.Remove(500))
+	assert.Equal(t, false, s.Contains(500))
+	assert.Equal(t, true, s.Contains(499))
+	assert.Equal(t, true, s.Contains(501))
+	assert.Equal(t, 2, s.tree.Len())
+
+	assert.Equal(t, uint64(12), s.AddRange(495, 1010))
+	assert.Equal(t, uint64(1011), s.Cardinality())
+	var runs [][2]uint64
+	s.Runs(func(start, end uint64) bool {
+		runs = append(runs, [2]uint64{start, end})
+		return true
+	})
+	assert.Equal(t, [][2]uint64{{0, 1010}}, runs)
+
+	assert.Equal(t, uint64(11), s.RemoveRange(10, 20))
+	assert.Equal(t, uint64(0), s.RemoveRange(10, 20))
+	var vals []uint64
+	s.Scan(func(v uint64) bool {
+		vals = append(vals, v)
+		return len(vals) < 12
+	})
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 21, 22}, vals)
+
+	s.Add(math.MaxUint64)
+	s.Add(math.MaxUint64 - 1)
+	assert.Equal(t, true, s.Contains(math.MaxUint64))
+	assert.Equal(t, true, s.Remove(math.MaxUint64))
+	assert.Equal(t, false, s.Contains(math.MaxUint64))
+}
+
+func TestRangeSetRandom(t *testing.T) {
+	var s RangeSet
+	exp := make(map[uint64]bool)
+	for i := 0; i < 20000; i++ {
+		v := uint64(rand.Intn(5000))
+		if rand.Intn(3) == 0 {
+			assert.Equal(t, exp[v], s.Remove(v))
+			delete(exp, v)
+		} else {
+			assert.Equal(t, !exp[v], s.Add(v))
+			exp[v] = true
+		}
+	}
+	assert.Equal(t, uint64(len(exp)), s.Cardinality())
+	for v := uint64(0); v < 5000; v++ {
+		assert.Equal(t, exp[v], s.Contains(v))
+	}
+	// runs are maximal
+	var prev [2]uint64
+	first := true
+	s.Runs(func(start, end uint64) bool {
+		if !first && prev[1]+1 >= start {
+			t.Fatalf("runs %v and %v not merged", prev, [2]uint64{start, end})
+		}
+		prev, first = [2]uint64{start, end}, false
+		return true
+	})
+}
+
+func TestRangeSetMaxUint64(t *testing.T) {
+	var s RangeSet
+	assert.Equal(t, true, s.Add(math.MaxUint64))
+	assert.Equal(t, false, s.Add(math.MaxUint64))
+	assert.Equal(t, uint64(1), s.Cardinality())
+	assert.Equal(t, true, s.Remove(math.MaxUint64))
+	assert.Equal(t, uint64(0), s.Cardinality())
+
+	assert.Equal(t, uint64(math.MaxUint64-9), s.AddRange(10, math.MaxUint64))
+	assert.Equal(t, true, s.Remove(10))
+	assert.Equal(t, uint64(math.MaxUint64-10), s.Cardinality())
+	assert.Equal(t, false, s.Contains(10))
+	assert.Equal(t, true, s.Contains(11))
+	assert.Equal(t, true, s.Contains(math.MaxUint64))
+	assert.Equal(t, true, s.Add(10))
+	var runs [][2]uint64
+	s.Runs(func(start, end uint64) bool {
+		runs = append(runs, [2]uint64{start, end})
+		return true
+	})
+	assert.Equal(t, [][2]uint64{{10, math.MaxUint64}}, runs)
+}
+
+func TestRangeSetFull(t *testing.T) {
+	var s RangeSet
+	// 2^64 values saturate at MaxUint64
+	assert.Equal(t, uint64(math.MaxUint64), s.AddRange(0, math.MaxUint64))
+	assert.Equal(t, uint64(math.MaxUint64), s.Cardinality())
+	assert.Equal(t, true, s.Contains(5))
+	assert.Equal(t, uint64(0), s.AddRange(5, 10))
+
+	assert.Equal(t, true, s.Remove(5))
+	assert.Equal(t, uint64(math.MaxUint64), s.Cardinality())
+	assert.Equal(t, true, s.Add(5))
+	assert.Equal(t, uint64(math.MaxUint64), s.Cardinality())
+
+	assert.Equal(t, uint64(math.MaxUint64-9), s.RemoveRange(10, math.MaxUint64))
+	assert.Equal(t, uint64(10), s.Cardinality())
+	assert.Equal(t, uint64(math.MaxUint64-9), s.AddRange(10, math.MaxUint64))
+	assert.Equal(t, uint64(math.MaxUint64), s.RemoveRange(0, math.MaxUint64))
+	assert.Equal(t, uint64(0), s.Cardinality())
+
+	// full set made of two runs
+	s.AddRange(0, 100)
+	assert.Equal(t, uint64(math.MaxUint64-100), s.AddRange(101, math.MaxUint64))
+	assert.Equal(t, uint64(math.MaxUint64), s.Cardinality())
+}
@@ -0,0 +1,241 @@
+package btreeset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+// opSnapshot mark end of snapshot in replication stream
+const opSnapshot byte = 3
+
+// MaxStreamKeySize is the longest key in replication stream. StreamWriter
+// fail with ErrKeyTooLarge on longer key, Follower treat it as corruption
+var MaxStreamKeySize = 1 << 20
+
+// ErrSequence returned by Follower on gap in sequence numbers
+var ErrSequence = errors.New("btreeset: unexpected sequence number")
+
+// stream record: crc32(4) | op(1) | uvarint seq | uvarint len | key
+func appendStreamRecord(buf []byte, op byte, seq uint64, key []byte) []byte {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0, op)
+	buf = binary.AppendUvarint(buf, seq)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	binary.BigEndian.PutUint32(buf[start:], crc32.Checksum(buf[start+4:], crcTable))
+	return buf
+}
+
+func readStreamRecord(r *bufio.Reader) (op byte, seq uint64, key []byte, err error) {
+	var hdr [5]byte
+	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+		return
+	}
+	if seq, err = binary.ReadUvarint(r); err != nil {
+		return 0, 0, nil, io.ErrUnexpectedEOF
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, 0, nil, io.ErrUnexpectedEOF
+	}
+	// length is not checked by crc yet, don't trust it
+	if size > uint64(MaxStreamKeySize) {
+		return 0, 0, nil, ErrCorrupted
+	}
+	key = make([]byte, size)
+	if _, err = io.ReadFull(r, key); err != nil {
+		return 0, 0, nil, io.ErrUnexpectedEOF
+	}
+	rec := appendStreamRecord(nil, hdr[4], seq, key)
+	if binary.BigEndian.Uint32(hdr[:]) != binary.BigEndian.Uint32(rec) {
+		return 0, 0, nil, ErrCorrupted
+	}
+	return hdr[4], seq, key, nil
+}
+
+// StreamWriter write snapshot of set and then all its changes to writer
+type StreamWriter struct {
+	w       *bufio.Writer
+	watcher *Watcher
+	snap    *BTreeSet
+	seq     uint64
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []batchOp
+	closed bool
+	err    error
+	done   chan struct{}
+}
+
+// NewStreamWriter start replication of tr to w in background
+// It must be called and closed by goroutine which modify tr
+func NewStreamWriter(tr *BTreeSet, w io.Writer) *StreamWriter {
+	s := &StreamWriter{w: bufio.NewWriter(w), snap: tr.Copy(), done: make(chan struct{})}
+	s.cond = sync.NewCond(&s.mu)
+	s.watcher = tr.Watch(nil, func(ev Event) {
+		if ev.Op == OpSet && ev.Replaced {
+			return
+		}
+		op := batchOp{op: opSet, key: append([]byte(nil), ev.Key...)}
+		if ev.Op == OpDelete {
+			op.op = opDelete
+		}
+		s.mu.Lock()
+		if s.err == nil {
+			s.queue = append(s.queue, op)
+		}
+		s.mu.Unlock()
+		s.cond.Signal()
+	})
+	go s.run()
+	return s
+}
+
+func (s *StreamWriter) write(op byte, key []byte) error {
+	if len(key) > MaxStreamKeySize {
+		return ErrKeyTooLarge
+	}
+	s.seq++
+	_, err := s.w.Write(appendStreamRecord(nil, op, s.seq, key))
+	return err
+}
+
+func (s *StreamWriter) run() {
+	defer close(s.done)
+	var err error
+	s.snap.Scan(func(key []byte) bool {
+		err = s.write(opSet, key)
+		return err == nil
+	})
+	if err == nil {
+		err = s.write(opSnapshot, nil)
+	}
+	s.snap = nil
+	for err == nil {
+		queue, closed := s.wait()
+		for _, op := range queue {
+			if err = s.write(op.op, op.key); err != nil {
+				break
+			}
+		}
+		// flush after every drained queue, so follower get changes without delay
+		if err == nil {
+			err = s.w.Flush()
+		}
+		if closed {
+			break
+		}
+	}
+	s.mu.Lock()
+	s.err = err
+	s.queue = nil
+	s.mu.Unlock()
+}
+
+// wait return queued changes, blocking while queue is empty
+func (s *StreamWriter) wait() (queue []batchOp, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	queue, s.queue = s.queue, nil
+	return queue, s.closed
+}
+
+// Close stop watching, write pending changes and return first write error
+func (s *StreamWriter) Close() error {
+	s.watcher.Stop()
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Signal()
+	<-s.done
+	return s.err
+}
+
+// Follower apply replication stream to set
+type Follower struct {
+	tr   *BTreeSet
+	mu   sync.Locker
+	seq  uint64
+	snap *BTreeSet // snapshot in progress
+}
+
+// NewFollower return follower which apply stream to tr
+// mu, if not nil, is locked while changes are applied
+func NewFollower(tr *BTreeSet, mu sync.Locker) *Follower {
+	return &Follower{tr: tr, mu: mu}
+}
+
+// Seq return sequence number of last applied record
+func (f *Follower) Seq() uint64 {
+	if f.mu != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+	}
+	return f.seq
+}
+
+// Apply read records from r until EOF
+// Snapshot is collected aside and merged into set at once, so set
+// keep old content until snapshot is complete
+func (f *Follower) Apply(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		op, seq, key, err := readStreamRecord(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = f.apply(op, seq, key); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *Follower) apply(op byte, seq uint64, key []byte) error {
+	if f.mu != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+	}
+	if seq == 1 {
+		// new stream start with snapshot
+		f.seq, f.snap = 0, &BTreeSet{}
+	}
+	if seq != f.seq+1 {
+		return ErrSequence
+	}
+	f.seq = seq
+	switch {
+	case op == opSnapshot:
+		if f.snap == nil {
+			return ErrCorrupted
+		}
+		// only changed keys are touched, so watchers of set see the difference
+		Diff(f.tr.Copy(), f.snap, func(key []byte) bool {
+			f.tr.Set(key)
+			return true
+		}, func(key []byte) bool {
+			f.tr.Delete(key)
+			return true
+		})
+		f.snap = nil
+	case f.snap != nil && op == opSet:
+		f.snap.Set(key)
+	case op == opSet:
+		f.tr.Set(key)
+	case op == opDelete && f.snap == nil:
+		f.tr.Delete(key)
+	default:
+		return ErrCorrupted
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package btreeset
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplication(t *testing.T) {
+	var leader BTreeSet
+	for i := 0; i < 1000; i++ {
+		leader.Set([]byte(fmt.Sprintf("%04d", i)))
+	}
+	r, w := io.Pipe()
+	s := NewStreamWriter(&leader, w)
+
+	var follower BTreeSet
+	follower.Set([]byte("stale"))
+	var mu sync.Mutex
+	f := NewFollower(&follower, &mu)
+	done := make(chan error)
+	go func() {
+		done <- f.Apply(r)
+	}()
+
+	for i := 0; i < 1000; i += 2 {
+		leader.Delete([]byte(fmt.Sprintf("%04d", i)))
+	}
+	leader.Set([]byte("new"))
+	leader.Set([]byte("new"))
+	assert.NoError(t, s.Close())
+	w.Close()
+	assert.NoError(t, <-done)
+
+	// snapshot 1000 + end + 500 deletes + 1 set
+	assert.Equal(t, uint64(1502), f.Seq())
+	assert.Equal(t, leader.Len(), follower.Len())
+	var a, b []string
+	leader.Scan(func(key []byte) bool {
+		a = append(a, string(key))
+		return true
+	})
+	follower.Scan(func(key []byte) bool {
+		b = append(b, string(key))
+		return true
+	})
+	assert.Equal(t, a, b)
+}
+
+func TestReplicationTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	var leader BTreeSet
+	leader.Set([]byte("a"))
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s := NewStreamWriter(&leader, conn)
+		leader.Set([]byte("b"))
+		leader.Delete([]byte("a"))
+		s.Close()
+		conn.Close()
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	var follower BTreeSet
+	assert.NoError(t, NewFollower(&follower, nil).Apply(conn))
+	assert.Equal(t, 1, follower.Len())
+	assert.Equal(t, true, follower.Has([]byte("b")))
+}
+
+func TestFollowerErrors(t *testing.T) {
+	var buf []byte
+	buf = appendStreamRecord(buf, opSet, 1, []byte("a"))
+	buf = appendStreamRecord(buf, opSet, 3, []byte("b"))
+	var tr BTreeSet
+	assert.Equal(t, ErrSequence, NewFollower(&tr, nil).Apply(bytes.NewReader(buf)))
+
+	buf = appendStreamRecord(nil, opSet, 1, []byte("a"))
+	buf[len(buf)-1] ^= 1
+	assert.Equal(t, ErrCorrupted, NewFollower(&tr, nil).Apply(bytes.NewReader(buf)))
+
+	// hostile length is rejected before allocation
+	buf = []byte{1, 2, 3, 4, opSet, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}
+	assert.Equal(t, ErrCorrupted, NewFollower(&tr, nil).Apply(bytes.NewReader(buf)))
+
+	var leader BTreeSet
+	leader.Set(make([]byte, MaxStreamKeySize+1))
+	s := NewStreamWriter(&leader, io.Discard)
+	assert.Equal(t, ErrKeyTooLarge, s.Close())
+}
@@ -0,0 +1,177 @@
+package btreeset
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// ShardedSet is a set split into independently locked trees
+// Keys are partitioned by ranges or by hash, Ascend and Descend
+// merge keys of all shards in order
+type ShardedSet struct {
+	shards []shard
+	bounds [][]byte // shard i hold keys < bounds[i], nil for hash partitioning
+}
+
+type shard struct {
+	mu sync.RWMutex
+	tr BTreeSet
+}
+
+// NewRangeSharded return set with len(bounds)+1 shards, shard i hold
+// keys within the range [bounds[i-1], bounds[i]), bounds must be sorted
+func NewRangeSharded(bounds [][]byte) *ShardedSet {
+	return &ShardedSet{shards: make([]shard, len(bounds)+1), bounds: bounds}
+}
+
+// NewHashSharded return set with n shards, keys are spread by hash
+func NewHashSharded(n int) *ShardedSet {
+	if n < 1 {
+		n = 1
+	}
+	return &ShardedSet{shards: make([]shard, n)}
+}
+
+func (s *ShardedSet) shard(key []byte) *shard {
+	if s.bounds != nil {
+		i := sort.Search(len(s.bounds), func(i int) bool {
+			return bytes.Compare(key, s.bounds[i]) < 0
+		})
+		return &s.shards[i]
+	}
+	return &s.shards[keyHash(key)%uint64(len(s.shards))]
+}
+
+// Set or replace a key
+func (s *ShardedSet) Set(key []byte) (replaced bool) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.tr.Set(key)
+}
+
+// Has return true if key exists
+func (s *ShardedSet) Has(key []byte) bool {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.tr.Has(key)
+}
+
+// Delete a key
+func (s *ShardedSet) Delete(key []byte) (deleted bool) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.tr.Delete(key)
+}
+
+// Len returns the number of keys in all shards
+func (s *ShardedSet) Len() (n int) {
+	for i := range s.shards {
+		s.shards[i].mu.RLock()
+		n += s.shards[i].tr.Len()
+		s.shards[i].mu.RUnlock()
+	}
+	return
+}
+
+// rlock all shards, in order, so readers don't deadlock
+func (s *ShardedSet) rlock() {
+	for i := range s.shards {
+		s.shards[i].mu.RLock()
+	}
+}
+
+func (s *ShardedSet) runlock() {
+	for i := range s.shards {
+		s.shards[i].mu.RUnlock()
+	}
+}
+
+// Ascend all shards within the range [pivot, last]
+// Shards are locked for reading while iterating
+func (s *ShardedSet) Ascend(pivot []byte, iter func(key []byte) bool) {
+	s.rlock()
+	defer s.runlock()
+	h := &cursorHeap{}
+	for i := range s.shards {
+		c := &cursor{}
+		c.seek(&s.shards[i].tr, pivot)
+		if c.valid() {
+			h.cursors = append(h.cursors, c)
+		}
+	}
+	s.merge(h, iter, (*cursor).next)
+}
+
+// Descend all shards within the range [pivot, first]
+// Shards are locked for reading while iterating
+func (s *ShardedSet) Descend(pivot []byte, iter func(key []byte) bool) {
+	s.rlock()
+	defer s.runlock()
+	h := &cursorHeap{reverse: true}
+	for i := range s.shards {
+		c := &cursor{}
+		c.seekReverse(&s.shards[i].tr, pivot)
+		if c.valid() {
+			h.cursors = append(h.cursors, c)
+		}
+	}
+	s.merge(h, iter, (*cursor).prev)
+}
+
+// Scan all keys of all shards
+func (s *ShardedSet) Scan(iter func(key []byte) bool) {
+	s.Ascend(nil, iter)
+}
+
+func (s *ShardedSet) merge(h *cursorHeap, iter func(key []byte) bool, step func(c *cursor)) {
+	heap.Init(h)
+	for h.Len() > 0 {
+		c := h.cursors[0]
+		if !iter(c.key()) {
+			return
+		}
+		step(c)
+		if c.valid() {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+}
+
+// cursorHeap order cursors by current key
+type cursorHeap struct {
+	cursors []*cursor
+	reverse bool
+}
+
+func (h *cursorHeap) Len() int {
+	return len(h.cursors)
+}
+
+func (h *cursorHeap) Less(i, j int) bool {
+	c := bytes.Compare(h.cursors[i].key(), h.cursors[j].key())
+	if h.reverse {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (h *cursorHeap) Swap(i, j int) {
+	h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i]
+}
+
+func (h *cursorHeap) Push(x interface{}) {
+	h.cursors = append(h.cursors, x.(*cursor))
+}
+
+func (h *cursorHeap) Pop() interface{} {
+	c := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return c
+}
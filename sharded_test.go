@@ -0,0 +1,98 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	var tr BTreeSet
+	for i := 0; i < 5000; i += 2 {
+		tr.Set([]byte(fmt.Sprintf("%04d", i)))
+	}
+	var c cursor
+	for _, p := range []int{-1, 0, 1, 777, 2500, 4998, 4999} {
+		pivot := []byte(fmt.Sprintf("%04d", p))
+		var exp, got []string
+		tr.Ascend(pivot, func(key []byte) bool {
+			exp = append(exp, string(key))
+			return true
+		})
+		for c.seek(&tr, pivot); c.valid(); c.next() {
+			got = append(got, string(c.key()))
+		}
+		assert.Equal(t, exp, got, p)
+
+		exp, got = nil, nil
+		tr.Descend(pivot, func(key []byte) bool {
+			exp = append(exp, string(key))
+			return true
+		})
+		for c.seekReverse(&tr, pivot); c.valid(); c.prev() {
+			got = append(got, string(c.key()))
+		}
+		assert.Equal(t, exp, got, p)
+	}
+	var n int
+	for c.last(&tr); c.valid(); c.prev() {
+		n++
+	}
+	assert.Equal(t, tr.Len(), n)
+}
+
+func TestShardedSet(t *testing.T) {
+	for _, s := range []*ShardedSet{
+		NewHashSharded(8),
+		NewRangeSharded([][]byte{[]byte("0250"), []byte("0500"), []byte("0750")}),
+	} {
+		var ref BTreeSet
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for w := 0; w < runtime.NumCPU(); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					key := []byte(fmt.Sprintf("%04d", rand.Intn(1000)))
+					s.Set(key)
+					mu.Lock()
+					ref.Set(key)
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, ref.Len(), s.Len())
+		for _, pivot := range []string{"", "0100", "0500", "0999", "1"} {
+			var exp, got []string
+			ref.Ascend([]byte(pivot), func(key []byte) bool {
+				exp = append(exp, string(key))
+				return true
+			})
+			s.Ascend([]byte(pivot), func(key []byte) bool {
+				got = append(got, string(key))
+				return true
+			})
+			assert.Equal(t, exp, got)
+			exp, got = nil, nil
+			ref.Descend([]byte(pivot), func(key []byte) bool {
+				exp = append(exp, string(key))
+				return len(exp) < 100
+			})
+			s.Descend([]byte(pivot), func(key []byte) bool {
+				got = append(got, string(key))
+				return len(got) < 100
+			})
+			assert.Equal(t, exp, got)
+		}
+		key := []byte(ref.First())
+		assert.Equal(t, true, s.Has(key))
+		assert.Equal(t, true, s.Delete(key))
+		assert.Equal(t, false, s.Has(key))
+	}
+}
@@ -0,0 +1,151 @@
+package btreeset
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+)
+
+// Stats is a summary of tree structure
+type Stats struct {
+	Height        int
+	Nodes         int
+	LeafNodes     int
+	InternalNodes int
+	// NodesPerLevel from root to leaves
+	NodesPerLevel []int
+	// AvgFill is average number of items in node divided by max
+	AvgFill float64
+	Keys    int
+	// KeySizes is histogram of key lengths, KeySizes[i] counts keys
+	// with bits.Len(len(key)) == i: 0, 1, 2-3, 4-7, 8-15...
+	KeySizes []int
+}
+
+// Stats walk tree and return its summary
+func (tr *BTreeSet) Stats() (st Stats) {
+	if tr.root == nil {
+		return
+	}
+	st.Height = tr.height
+	st.NodesPerLevel = make([]int, tr.height+1)
+	tr.root.stats(&st, 0, tr.height)
+	st.AvgFill = float64(st.Keys) / float64(st.Nodes*(maxItems-1))
+	return
+}
+
+func (n *node) stats(st *Stats, level, height int) {
+	st.Nodes++
+	st.NodesPerLevel[level]++
+	st.Keys += n.numItems
+	for i := 0; i < n.numItems; i++ {
+		b := bits.Len(uint(len(n.items[i].key)))
+		for len(st.KeySizes) <= b {
+			st.KeySizes = append(st.KeySizes, 0)
+		}
+		st.KeySizes[b]++
+	}
+	if height == 0 {
+		st.LeafNodes++
+		return
+	}
+	st.InternalNodes++
+	for i := 0; i <= n.numItems; i++ {
+		n.children[i].stats(st, level+1, height-1)
+	}
+}
+
+// Validate check tree invariants: order of keys, number of items
+// in nodes, same depth of leaves, counters and node summaries
+func (tr *BTreeSet) Validate() error {
+	if tr.root == nil {
+		if tr.length != 0 || tr.height != 0 || tr.nodes != 0 || tr.keyBytes != 0 {
+			return fmt.Errorf("btreeset: empty tree with length %d, height %d, nodes %d, key bytes %d",
+				tr.length, tr.height, tr.nodes, tr.keyBytes)
+		}
+		return nil
+	}
+	v := validator{tr: tr}
+	if err := v.node(tr.root, tr.height, true, nil, nil); err != nil {
+		return err
+	}
+	if v.keys != tr.length {
+		return fmt.Errorf("btreeset: length %d, found %d keys", tr.length, v.keys)
+	}
+	if v.nodes != tr.nodes {
+		return fmt.Errorf("btreeset: node counter %d, found %d nodes", tr.nodes, v.nodes)
+	}
+	if v.keyBytes != tr.keyBytes {
+		return fmt.Errorf("btreeset: key bytes counter %d, found %d", tr.keyBytes, v.keyBytes)
+	}
+	return nil
+}
+
+type validator struct {
+	tr                    *BTreeSet
+	keys, nodes, keyBytes int
+}
+
+// node check subtree with keys within the range (lo, hi), nil is no bound
+func (v *validator) node(n *node, height int, root bool, lo, hi []byte) error {
+	if n == nil {
+		return fmt.Errorf("btreeset: nil node at height %d", height)
+	}
+	v.nodes++
+	v.keys += n.numItems
+	min := minItems
+	if root {
+		min = 1
+	}
+	if n.numItems < min || n.numItems >= maxItems {
+		return fmt.Errorf("btreeset: node with %d items at height %d, expected [%d, %d)",
+			n.numItems, height, min, maxItems)
+	}
+	for i := 0; i < n.numItems; i++ {
+		key := n.items[i].key
+		v.keyBytes += len(key)
+		if i > 0 && bytes.Compare(n.items[i-1].key, key) >= 0 {
+			return fmt.Errorf("btreeset: keys %q and %q out of order", n.items[i-1].key, key)
+		}
+		if (lo != nil && bytes.Compare(key, lo) <= 0) || (hi != nil && bytes.Compare(key, hi) >= 0) {
+			return fmt.Errorf("btreeset: key %q out of parent range (%q, %q)", key, lo, hi)
+		}
+	}
+	// unused slots must be empty, or removed keys and nodes are not collected
+	for i := n.numItems; i < maxItems; i++ {
+		if n.items[i].key != nil {
+			return fmt.Errorf("btreeset: item %d of node with %d items", i, n.numItems)
+		}
+	}
+	for i := n.numItems + 1; i <= maxItems; i++ {
+		if n.children[i] != nil {
+			return fmt.Errorf("btreeset: child %d of node with %d items", i, n.numItems)
+		}
+	}
+	if height == 0 {
+		if n.children[0] != nil {
+			return fmt.Errorf("btreeset: leaf with children")
+		}
+	} else {
+		for i := 0; i <= n.numItems; i++ {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = n.items[i-1].key
+			}
+			if i < n.numItems {
+				chi = n.items[i].key
+			}
+			if err := v.node(n.children[i], height-1, false, clo, chi); err != nil {
+				return err
+			}
+		}
+	}
+	if v.tr.augment != nil {
+		fixed := *n
+		v.tr.augment(&fixed, height)
+		if !bytes.Equal(fixed.aug, n.aug) {
+			return fmt.Errorf("btreeset: node summary %x, expected %x", n.aug, fixed.aug)
+		}
+	}
+	return nil
+}
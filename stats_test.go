@@ -0,0 +1,56 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	var tr BTreeSet
+	assert.Equal(t, Stats{}, tr.Stats())
+	assert.NoError(t, tr.Validate())
+	for i := 0; i < 100000; i++ {
+		tr.Set([]byte(fmt.Sprint(i)))
+	}
+	st := tr.Stats()
+	assert.Equal(t, tr.height, st.Height)
+	assert.Equal(t, 100000, st.Keys)
+	assert.Equal(t, tr.nodes, st.Nodes)
+	assert.Equal(t, st.Nodes, st.LeafNodes+st.InternalNodes)
+	assert.Equal(t, 1, st.NodesPerLevel[0])
+	assert.Equal(t, st.LeafNodes, st.NodesPerLevel[st.Height])
+	assert.Equal(t, true, st.AvgFill > 0.4 && st.AvgFill <= 1)
+	// len 1: 0-9, len 2-3: 10-999, len 4-7: 1000-99999
+	assert.Equal(t, []int{0, 10, 990, 99000}, st.KeySizes)
+}
+
+func TestValidate(t *testing.T) {
+	var tr BTreeSet
+	tr.EnableHash()
+	keys := randKeys(20000)
+	for i, k := range keys {
+		tr.Set([]byte(k))
+		if i%997 == 0 {
+			assert.NoError(t, tr.Validate())
+		}
+	}
+	cp := tr.Copy()
+	for i, j := range rand.Perm(len(keys)) {
+		tr.Delete([]byte(keys[j]))
+		if i%997 == 0 {
+			assert.NoError(t, tr.Validate())
+		}
+	}
+	assert.NoError(t, tr.Validate())
+	assert.NoError(t, cp.Validate())
+
+	// broken trees are reported
+	cp.length++
+	assert.Error(t, cp.Validate())
+	cp.length--
+	cp.root.items[0], cp.root.items[1] = cp.root.items[1], cp.root.items[0]
+	assert.Error(t, cp.Validate())
+}
@@ -0,0 +1,166 @@
+package btreeset
+
+import (
+	"sync"
+	"time"
+)
+
+// ExpiringSet is a set where keys may expire
+// Expired keys are absent for readers and removed by Sweep
+type ExpiringSet struct {
+	mu        sync.Mutex
+	tr        BTreeSet
+	deadlines map[string]int64
+	index     BTreeSet // deadline | key
+	// Now return current time, time.Now if nil
+	Now func() time.Time
+}
+
+func (s *ExpiringSet) now() int64 {
+	if s.Now != nil {
+		return s.Now().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+func deadlineKey(deadline int64, key []byte) []byte {
+	return append(int64ToBinary(deadline), key...)
+}
+
+func (s *ExpiringSet) expired(key []byte, now int64) bool {
+	deadline, ok := s.deadlines[string(key)]
+	return ok && deadline <= now
+}
+
+// Set a key without expiration
+func (s *ExpiringSet) Set(key []byte) (replaced bool) {
+	return s.SetWithTTL(key, 0)
+}
+
+// SetWithTTL set a key which expire after ttl, ttl <= 0 means never
+func (s *ExpiringSet) SetWithTTL(key []byte, ttl time.Duration) (replaced bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if deadline, ok := s.deadlines[string(key)]; ok {
+		replaced = deadline > now
+		s.index.Delete(deadlineKey(deadline, key))
+		delete(s.deadlines, string(key))
+	} else {
+		replaced = s.tr.Has(key)
+	}
+	if ttl > 0 {
+		if s.deadlines == nil {
+			s.deadlines = make(map[string]int64)
+		}
+		deadline := now + int64(ttl)
+		s.deadlines[string(key)] = deadline
+		s.index.Set(deadlineKey(deadline, key))
+	}
+	s.tr.Set(key)
+	return
+}
+
+// Has return true if key exists and not expired
+func (s *ExpiringSet) Has(key []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tr.Has(key) && !s.expired(key, s.now())
+}
+
+// TTL return time left for key, 0 if key never expire
+func (s *ExpiringSet) TTL(key []byte) (ttl time.Duration, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if !s.tr.Has(key) || s.expired(key, now) {
+		return 0, false
+	}
+	if deadline, ok := s.deadlines[string(key)]; ok {
+		return time.Duration(deadline - now), true
+	}
+	return 0, true
+}
+
+// Delete a key
+func (s *ExpiringSet) Delete(key []byte) (deleted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := s.expired(key, s.now())
+	if deadline, ok := s.deadlines[string(key)]; ok {
+		s.index.Delete(deadlineKey(deadline, key))
+		delete(s.deadlines, string(key))
+	}
+	return s.tr.Delete(key) && !expired
+}
+
+// Len returns the number of keys, expired but not swept keys included
+func (s *ExpiringSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tr.Len()
+}
+
+// Ascend not expired keys within the range [pivot, last]
+// Set is locked while iterating
+func (s *ExpiringSet) Ascend(pivot []byte, iter func(key []byte) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.tr.Ascend(pivot, func(key []byte) bool {
+		return s.expired(key, now) || iter(key)
+	})
+}
+
+// Descend not expired keys within the range [pivot, first]
+// Set is locked while iterating
+func (s *ExpiringSet) Descend(pivot []byte, iter func(key []byte) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.tr.Descend(pivot, func(key []byte) bool {
+		return s.expired(key, now) || iter(key)
+	})
+}
+
+// Sweep remove expired keys, return number of removed keys
+func (s *ExpiringSet) Sweep() (removed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var expired [][]byte
+	s.index.Scan(func(key []byte) bool {
+		if binaryToInt64(key[:8]) > now {
+			return false
+		}
+		expired = append(expired, key)
+		return true
+	})
+	for _, key := range expired {
+		s.index.Delete(key)
+		delete(s.deadlines, string(key[8:]))
+		s.tr.Delete(key[8:])
+	}
+	return len(expired)
+}
+
+// StartSweeper run Sweep every interval in background until stop is called
+func (s *ExpiringSet) StartSweeper(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				s.Sweep()
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
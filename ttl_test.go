@@ -0,0 +1,58 @@
+package btreeset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiringSet(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := ExpiringSet{Now: func() time.Time { return now }}
+	assert.Equal(t, false, s.SetWithTTL([]byte("a"), time.Minute))
+	assert.Equal(t, false, s.SetWithTTL([]byte("b"), 2*time.Minute))
+	assert.Equal(t, false, s.Set([]byte("c")))
+	ttl, ok := s.TTL([]byte("a"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Minute, ttl)
+	ttl, ok = s.TTL([]byte("c"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, time.Duration(0), ttl)
+
+	now = now.Add(90 * time.Second)
+	assert.Equal(t, false, s.Has([]byte("a")))
+	assert.Equal(t, true, s.Has([]byte("b")))
+	assert.Equal(t, true, s.Has([]byte("c")))
+	var keys []string
+	s.Ascend(nil, func(key []byte) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	assert.Equal(t, []string{"b", "c"}, keys)
+	assert.Equal(t, 3, s.Len())
+
+	// expired key is not replaced
+	assert.Equal(t, false, s.SetWithTTL([]byte("a"), time.Minute))
+	// ttl may be removed
+	assert.Equal(t, true, s.Set([]byte("b")))
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, true, s.Has([]byte("b")))
+	assert.Equal(t, 1, s.Sweep())
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, 0, s.index.Len())
+	assert.Equal(t, 0, len(s.deadlines))
+}
+
+func TestExpiringSetSweeper(t *testing.T) {
+	var s ExpiringSet
+	s.SetWithTTL([]byte("a"), time.Millisecond)
+	stop := s.StartSweeper(time.Millisecond)
+	defer stop()
+	for i := 0; i < 1000 && s.Len() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 0, s.Len())
+	stop()
+}
@@ -0,0 +1,180 @@
+package btreeset
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrVersionNotFound returned by At for unknown or collected version
+var ErrVersionNotFound = errors.New("btreeset: version not found")
+
+// VersionedSet is a BTreeSet where every change commit new version
+// Versions share nodes with copy-on-write, so old version costs only
+// nodes changed after it
+type VersionedSet struct {
+	mu       sync.RWMutex
+	tr       BTreeSet
+	version  uint64
+	versions map[uint64]*version
+	// Retain is number of latest versions available for At, 0 - only
+	// latest, negative - keep all. Older versions are collected once no
+	// Snapshot holds them. Every kept version hold the nodes on paths
+	// changed after it, several nodes of ~10KB each per change
+	Retain int
+}
+
+type version struct {
+	tr      *BTreeSet
+	readers int
+}
+
+// Snapshot is a read-only view of VersionedSet at version
+type Snapshot struct {
+	vs      *VersionedSet
+	version uint64
+	tr      *BTreeSet
+}
+
+func (vs *VersionedSet) commit() uint64 {
+	if vs.versions == nil {
+		vs.versions = map[uint64]*version{0: {tr: &BTreeSet{}}}
+	}
+	vs.version++
+	vs.versions[vs.version] = &version{tr: vs.tr.Copy()}
+	vs.gc()
+	return vs.version
+}
+
+// gc drop versions out of Retain window without readers
+func (vs *VersionedSet) gc() {
+	if vs.Retain < 0 {
+		return
+	}
+	retain := uint64(vs.Retain)
+	if retain == 0 {
+		retain = 1
+	}
+	for v, ver := range vs.versions {
+		if v+retain <= vs.version && ver.readers == 0 {
+			delete(vs.versions, v)
+		}
+	}
+}
+
+// Set a key, return version with key
+func (vs *VersionedSet) Set(key []byte) (replaced bool, version uint64) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if vs.tr.Set(key) {
+		return true, vs.version
+	}
+	return false, vs.commit()
+}
+
+// Delete a key, return version without key
+func (vs *VersionedSet) Delete(key []byte) (deleted bool, version uint64) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if !vs.tr.Delete(key) {
+		return false, vs.version
+	}
+	return true, vs.commit()
+}
+
+// Has return true if key exists in latest version
+func (vs *VersionedSet) Has(key []byte) bool {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	return vs.tr.Has(key)
+}
+
+// Len returns the number of items in latest version
+func (vs *VersionedSet) Len() int {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	return vs.tr.Len()
+}
+
+// Version return latest version, it's 0 for empty set without changes
+func (vs *VersionedSet) Version() uint64 {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	return vs.version
+}
+
+// At open snapshot of set at version, snapshot must be closed
+func (vs *VersionedSet) At(v uint64) (*Snapshot, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if v == 0 && vs.versions == nil {
+		return &Snapshot{vs: vs, tr: &BTreeSet{}}, nil
+	}
+	ver, ok := vs.versions[v]
+	if !ok {
+		return nil, ErrVersionNotFound
+	}
+	ver.readers++
+	return &Snapshot{vs: vs, version: v, tr: ver.tr}, nil
+}
+
+// Latest open snapshot of latest version, snapshot must be closed
+// Unlike At(Version()) it can't miss version collected in between
+func (vs *VersionedSet) Latest() *Snapshot {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if vs.versions == nil {
+		return &Snapshot{vs: vs, tr: &BTreeSet{}}
+	}
+	ver := vs.versions[vs.version]
+	ver.readers++
+	return &Snapshot{vs: vs, version: vs.version, tr: ver.tr}
+}
+
+// Close release snapshot, so its version may be collected
+func (s *Snapshot) Close() {
+	if s.tr == nil {
+		return
+	}
+	s.vs.mu.Lock()
+	defer s.vs.mu.Unlock()
+	if ver, ok := s.vs.versions[s.version]; ok {
+		ver.readers--
+	}
+	s.vs.gc()
+	s.tr = nil
+}
+
+// Version of snapshot
+func (s *Snapshot) Version() uint64 {
+	return s.version
+}
+
+// Has return true if key exists
+func (s *Snapshot) Has(key []byte) bool {
+	return s.tr.Has(key)
+}
+
+// Len returns the number of items
+func (s *Snapshot) Len() int {
+	return s.tr.Len()
+}
+
+// Scan all items
+func (s *Snapshot) Scan(iter func(key []byte) bool) {
+	s.tr.Scan(iter)
+}
+
+// Reverse all items
+func (s *Snapshot) Reverse(iter func(key []byte) bool) {
+	s.tr.Reverse(iter)
+}
+
+// Ascend within the range [pivot, last]
+func (s *Snapshot) Ascend(pivot []byte, iter func(key []byte) bool) {
+	s.tr.Ascend(pivot, iter)
+}
+
+// Descend within the range [pivot, first]
+func (s *Snapshot) Descend(pivot []byte, iter func(key []byte) bool) {
+	s.tr.Descend(pivot, iter)
+}
@@ -0,0 +1,104 @@
+package btreeset
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTreeSetCopy(t *testing.T) {
+	var tr BTreeSet
+	keys := randKeys(5000)
+	for _, key := range keys {
+		tr.Set([]byte(key))
+	}
+	cp := tr.Copy()
+	for _, key := range keys[:2500] {
+		assert.Equal(t, true, tr.Delete([]byte(key)))
+	}
+	for i := 0; i < 1000; i++ {
+		cp.Set([]byte(fmt.Sprintf("new%d", i)))
+	}
+	assert.Equal(t, 2500, tr.Len())
+	assert.Equal(t, 6000, cp.Len())
+	for _, key := range keys {
+		assert.Equal(t, true, cp.Has([]byte(key)))
+	}
+	for _, key := range keys[:2500] {
+		assert.Equal(t, false, tr.Has([]byte(key)))
+	}
+	assert.Equal(t, false, tr.Has([]byte("new1")))
+}
+
+func TestVersionedSet(t *testing.T) {
+	vs := VersionedSet{Retain: -1}
+	snap, err := vs.At(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, snap.Len())
+	snap.Close()
+
+	_, v1 := vs.Set([]byte("a"))
+	_, v2 := vs.Set([]byte("b"))
+	replaced, v := vs.Set([]byte("b"))
+	assert.Equal(t, true, replaced)
+	assert.Equal(t, v2, v)
+	_, v3 := vs.Delete([]byte("a"))
+	assert.Equal(t, uint64(3), v3)
+
+	for _, c := range []struct {
+		v    uint64
+		a, b bool
+	}{{0, false, false}, {v1, true, false}, {v2, true, true}, {v3, false, true}} {
+		snap, err := vs.At(c.v)
+		assert.NoError(t, err)
+		assert.Equal(t, c.a, snap.Has([]byte("a")), c.v)
+		assert.Equal(t, c.b, snap.Has([]byte("b")), c.v)
+		snap.Close()
+	}
+	_, err = vs.At(10)
+	assert.Equal(t, ErrVersionNotFound, err)
+}
+
+func TestVersionedSetRetain(t *testing.T) {
+	vs := VersionedSet{Retain: 2}
+	vs.Set([]byte("0"))
+	held, err := vs.At(1)
+	assert.NoError(t, err)
+	for i := 1; i < 1000; i++ {
+		vs.Set([]byte(fmt.Sprint(rand.Int())))
+	}
+	assert.Equal(t, uint64(1000), vs.Version())
+	_, err = vs.At(500)
+	assert.Equal(t, ErrVersionNotFound, err)
+	snap, err := vs.At(999)
+	assert.NoError(t, err)
+	assert.Equal(t, 999, snap.Len())
+	snap.Close()
+
+	// held version survive until closed
+	assert.Equal(t, 1, held.Len())
+	assert.Equal(t, true, held.Has([]byte("0")))
+	snap, err = vs.At(1)
+	assert.NoError(t, err)
+	snap.Close()
+	held.Close()
+	_, err = vs.At(1)
+	assert.Equal(t, ErrVersionNotFound, err)
+	assert.Equal(t, 2, len(vs.versions))
+
+	// by default only latest and held versions are kept
+	var def VersionedSet
+	def.Set([]byte("a"))
+	held, err = def.At(1)
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		def.Set([]byte(fmt.Sprint(i)))
+	}
+	assert.Equal(t, 2, len(def.versions))
+	_, err = def.At(50)
+	assert.Equal(t, ErrVersionNotFound, err)
+	held.Close()
+	assert.Equal(t, 1, len(def.versions))
+}
@@ -0,0 +1,481 @@
+package btreeset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyncPolicy define when log is fsynced
+type SyncPolicy int
+
+const (
+	// SyncAlways fsync after every operation
+	SyncAlways SyncPolicy = iota
+	// SyncBatch fsync after BatchSize operations
+	SyncBatch
+	// SyncInterval fsync every Interval in background
+	SyncInterval
+)
+
+const (
+	opSet byte = iota + 1
+	opDelete
+)
+
+const (
+	walFile      = "wal"
+	snapshotFile = "snapshot"
+)
+
+// ErrCorrupted returned when snapshot checksum mismatch
+var ErrCorrupted = errors.New("btreeset: corrupted file")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WALOptions for OpenDurable
+type WALOptions struct {
+	Sync SyncPolicy
+	// BatchSize ops between fsync for SyncBatch
+	BatchSize int
+	// Interval between fsync for SyncInterval
+	Interval time.Duration
+	// CheckpointOps is number of logged ops after which snapshot is written
+	// and log truncated, 0 - only on Checkpoint()
+	CheckpointOps int
+}
+
+// DurableSet is a BTreeSet with write-ahead log
+// After failed fsync all writes return the error, reopen the set to
+// continue from log on disk
+type DurableSet struct {
+	mu      sync.RWMutex
+	tr      BTreeSet
+	dir     string
+	opt     WALOptions
+	log     *os.File
+	ops     int   // ops since checkpoint
+	pending int   // ops since fsync
+	err     error // write or fsync error, set reject writes after it
+	closed  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// OpenDurable open or create durable set in dir, nil opt mean SyncAlways
+func OpenDurable(dir string, opt *WALOptions) (*DurableSet, error) {
+	ds := &DurableSet{dir: dir, closed: make(chan struct{})}
+	if opt != nil {
+		ds.opt = *opt
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := ds.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	ds.log = f
+	if err = ds.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if ds.opt.Sync == SyncInterval && ds.opt.Interval > 0 {
+		ds.wg.Add(1)
+		go ds.syncLoop()
+	}
+	return ds, nil
+}
+
+func (ds *DurableSet) syncLoop() {
+	defer ds.wg.Done()
+	t := time.NewTicker(ds.opt.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ds.closed:
+			return
+		case <-t.C:
+			ds.Sync()
+		}
+	}
+}
+
+// replay log, torn or corrupted tail is truncated
+func (ds *DurableSet) replay() error {
+	st, err := ds.log.Stat()
+	if err != nil {
+		return err
+	}
+	offset, err := ds.readLog(bufio.NewReader(ds.log), st.Size())
+	if err != nil {
+		return err
+	}
+	if err = ds.log.Truncate(offset); err != nil {
+		return err
+	}
+	_, err = ds.log.Seek(offset, io.SeekStart)
+	return err
+}
+
+// readLog apply records of log with size, return offset of torn or
+// corrupted tail. Read errors are returned, so log is not cut by them
+func (ds *DurableSet) readLog(r *bufio.Reader, size int64) (int64, error) {
+	var offset int64
+	for {
+		op, key, n, err := readRecord(r, size-offset)
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF, ErrCorrupted:
+			return offset, nil
+		default:
+			return offset, err
+		}
+		switch op {
+		case opSet:
+			ds.tr.Set(key)
+		case opDelete:
+			ds.tr.Delete(key)
+		}
+		offset += n
+		ds.ops++
+	}
+}
+
+// record: crc32(4) | op(1) | uvarint len | key
+func appendRecord(buf []byte, op byte, key []byte) []byte {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0, op)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	binary.BigEndian.PutUint32(buf[start:], crc32.Checksum(buf[start+4:], crcTable))
+	return buf
+}
+
+// readRecord read record not longer than limit bytes
+func readRecord(r *bufio.Reader, limit int64) (op byte, key []byte, n int64, err error) {
+	var hdr [5]byte
+	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+		return
+	}
+	size, err := readUvarint(r)
+	if err != nil {
+		return
+	}
+	// length is not checked by crc yet, garbage tail may hold any
+	if size > uint64(limit) {
+		return 0, nil, 0, ErrCorrupted
+	}
+	key = make([]byte, size)
+	if _, err = io.ReadFull(r, key); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	rec := appendRecord(nil, hdr[4], key)
+	if binary.BigEndian.Uint32(hdr[:]) != binary.BigEndian.Uint32(rec) {
+		return 0, nil, 0, ErrCorrupted
+	}
+	return hdr[4], key, int64(len(rec)), nil
+}
+
+// append op to log. On failed write the log is cut back to previous
+// record, so next records don't follow torn one. If it can't be cut, or
+// fsync failed and state of log on disk is unknown, the set is failed
+// readUvarint is binary.ReadUvarint which return ErrCorrupted on
+// overflow and io.ErrUnexpectedEOF on EOF, as it's read after header
+func readUvarint(r *bufio.Reader) (uint64, error) {
+	var buf [binary.MaxVarintLen64]byte
+	for i := range buf {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		buf[i] = b
+		if b < 0x80 {
+			v, n := binary.Uvarint(buf[:i+1])
+			if n <= 0 {
+				return 0, ErrCorrupted
+			}
+			return v, nil
+		}
+	}
+	return 0, ErrCorrupted
+}
+
+func (ds *DurableSet) append(op byte, key []byte) error {
+	if ds.err != nil {
+		return ds.err
+	}
+	off, err := ds.log.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return ds.fail(err)
+	}
+	if _, err = ds.log.Write(appendRecord(nil, op, key)); err != nil {
+		if ds.rollback(off) != nil {
+			return ds.fail(err)
+		}
+		return err
+	}
+	ds.ops++
+	ds.pending++
+	if ds.opt.Sync == SyncAlways || (ds.opt.Sync == SyncBatch && ds.pending >= ds.opt.BatchSize) {
+		if err = ds.sync(); err != nil {
+			// op is not applied, so it must not reappear on replay
+			ds.rollback(off)
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback cut log at off
+func (ds *DurableSet) rollback(off int64) error {
+	if err := ds.log.Truncate(off); err != nil {
+		return err
+	}
+	_, err := ds.log.Seek(off, io.SeekStart)
+	return err
+}
+
+// fail reject further writes with err
+func (ds *DurableSet) fail(err error) error {
+	if ds.err == nil {
+		ds.err = err
+	}
+	return ds.err
+}
+
+// autoCheckpoint write snapshot after CheckpointOps logged ops
+// It is called once the op is applied, so snapshot include it
+func (ds *DurableSet) autoCheckpoint() error {
+	if ds.opt.CheckpointOps > 0 && ds.ops >= ds.opt.CheckpointOps {
+		return ds.checkpoint()
+	}
+	return nil
+}
+
+// Set or replace a key
+func (ds *DurableSet) Set(key []byte) (replaced bool, err error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.tr.Has(key) {
+		return true, nil
+	}
+	if err = ds.append(opSet, key); err != nil {
+		return
+	}
+	ds.tr.Set(key)
+	return false, ds.autoCheckpoint()
+}
+
+// Delete a key
+func (ds *DurableSet) Delete(key []byte) (deleted bool, err error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if !ds.tr.Has(key) {
+		return false, nil
+	}
+	if err = ds.append(opDelete, key); err != nil {
+		return
+	}
+	ds.tr.Delete(key)
+	return true, ds.autoCheckpoint()
+}
+
+// Has return true if key exists
+func (ds *DurableSet) Has(key []byte) bool {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.tr.Has(key)
+}
+
+// Len returns the number of items in the set
+func (ds *DurableSet) Len() int {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.tr.Len()
+}
+
+// Scan all items, set is locked for reading while iterating
+func (ds *DurableSet) Scan(iter func(key []byte) bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	ds.tr.Scan(iter)
+}
+
+// Ascend the set within the range [pivot, last]
+func (ds *DurableSet) Ascend(pivot []byte, iter func(key []byte) bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	ds.tr.Ascend(pivot, iter)
+}
+
+// Descend the set within the range [pivot, first]
+func (ds *DurableSet) Descend(pivot []byte, iter func(key []byte) bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	ds.tr.Descend(pivot, iter)
+}
+
+// Sync fsync log
+func (ds *DurableSet) Sync() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.sync()
+}
+
+func (ds *DurableSet) sync() error {
+	if ds.err != nil {
+		return ds.err
+	}
+	if ds.pending == 0 {
+		return nil
+	}
+	if err := ds.log.Sync(); err != nil {
+		// after failed fsync written pages may be lost, don't trust log
+		return ds.fail(err)
+	}
+	ds.pending = 0
+	return nil
+}
+
+// Checkpoint write snapshot of the set and truncate log
+func (ds *DurableSet) Checkpoint() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.checkpoint()
+}
+
+func (ds *DurableSet) checkpoint() error {
+	path := filepath.Join(ds.dir, snapshotFile)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err = writeSnapshot(f, &ds.tr); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if err = syncDir(ds.dir); err != nil {
+		return err
+	}
+	// log replayed over fresh snapshot is harmless, so truncate after rename
+	if err = ds.log.Truncate(0); err != nil {
+		return err
+	}
+	if _, err = ds.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err = ds.log.Sync(); err != nil {
+		return err
+	}
+	ds.ops, ds.pending = 0, 0
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// snapshot: uvarint count | (uvarint len | key)... | crc32(4)
+func writeSnapshot(w io.Writer, tr *BTreeSet) (err error) {
+	bw := bufio.NewWriter(w)
+	crc := crc32.New(crcTable)
+	mw := io.MultiWriter(bw, crc)
+	buf := binary.AppendUvarint(nil, uint64(tr.Len()))
+	if _, err = mw.Write(buf); err != nil {
+		return
+	}
+	tr.Scan(func(key []byte) bool {
+		buf = binary.AppendUvarint(buf[:0], uint64(len(key)))
+		buf = append(buf, key...)
+		_, err = mw.Write(buf)
+		return err == nil
+	})
+	if err != nil {
+		return
+	}
+	if err = binary.Write(bw, binary.BigEndian, crc.Sum32()); err != nil {
+		return
+	}
+	return bw.Flush()
+}
+
+func readSnapshot(r io.Reader, tr *BTreeSet) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(data) < 4 {
+		return ErrCorrupted
+	}
+	body := data[:len(data)-4]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(data[len(body):]) {
+		return ErrCorrupted
+	}
+	count, n := binary.Uvarint(body)
+	if n <= 0 {
+		return ErrCorrupted
+	}
+	body = body[n:]
+	for i := uint64(0); i < count; i++ {
+		size, n := binary.Uvarint(body)
+		if n <= 0 || uint64(len(body)-n) < size {
+			return ErrCorrupted
+		}
+		tr.Set(body[n : n+int(size) : n+int(size)])
+		body = body[n+int(size):]
+	}
+	return nil
+}
+
+func (ds *DurableSet) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(ds.dir, snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readSnapshot(f, &ds.tr)
+}
+
+// Close sync and close log
+func (ds *DurableSet) Close() error {
+	close(ds.closed)
+	ds.wg.Wait()
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	err := ds.sync()
+	if cerr := ds.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
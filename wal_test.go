@@ -0,0 +1,197 @@
+package btreeset
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurableSet(t *testing.T) {
+	dir := t.TempDir()
+	ds, err := OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		replaced, err := ds.Set([]byte(fmt.Sprintf("%03d", i)))
+		assert.NoError(t, err)
+		assert.Equal(t, false, replaced)
+	}
+	replaced, _ := ds.Set([]byte("000"))
+	assert.Equal(t, true, replaced)
+	deleted, err := ds.Delete([]byte("050"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, deleted)
+	assert.NoError(t, ds.Close())
+
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 99, ds.Len())
+	assert.Equal(t, false, ds.Has([]byte("050")))
+	assert.Equal(t, true, ds.Has([]byte("099")))
+
+	// torn record at the end of log is dropped
+	assert.NoError(t, ds.Close())
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	rec := appendRecord(nil, opSet, []byte("torn"))
+	f.Write(rec[:len(rec)-1])
+	f.Close()
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 99, ds.Len())
+	ds.Set([]byte("after"))
+	assert.NoError(t, ds.Close())
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ds.Has([]byte("after")))
+	assert.Equal(t, false, ds.Has([]byte("torn")))
+	assert.NoError(t, ds.Close())
+}
+
+func TestDurableSetCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	ds, err := OpenDurable(dir, &WALOptions{Sync: SyncBatch, BatchSize: 10, CheckpointOps: 50})
+	assert.NoError(t, err)
+	for i := 0; i < 120; i++ {
+		_, err = ds.Set([]byte(fmt.Sprintf("%03d", i)))
+		assert.NoError(t, err)
+	}
+	st, err := os.Stat(filepath.Join(dir, walFile))
+	assert.NoError(t, err)
+	// 20 records after second checkpoint
+	assert.Equal(t, int64(20*len(appendRecord(nil, opSet, []byte("000")))), st.Size())
+	ds.Delete([]byte("000"))
+	assert.NoError(t, ds.Checkpoint())
+	st, _ = os.Stat(filepath.Join(dir, walFile))
+	assert.Equal(t, int64(0), st.Size())
+	assert.NoError(t, ds.Close())
+
+	ds, err = OpenDurable(dir, &WALOptions{Sync: SyncInterval, Interval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 119, ds.Len())
+	var first []byte
+	ds.Scan(func(key []byte) bool {
+		first = key
+		return false
+	})
+	assert.Equal(t, []byte("001"), first)
+	assert.NoError(t, ds.Close())
+
+	// corrupted snapshot is reported
+	path := filepath.Join(dir, snapshotFile)
+	data, _ := os.ReadFile(path)
+	data[1] ^= 0xff
+	os.WriteFile(path, data, 0644)
+	_, err = OpenDurable(dir, nil)
+	assert.Equal(t, ErrCorrupted, err)
+}
+
+func TestDurableSetAutoCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	ds, err := OpenDurable(dir, &WALOptions{CheckpointOps: 5})
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = ds.Set([]byte(fmt.Sprint(i)))
+		assert.NoError(t, err)
+	}
+	st, _ := os.Stat(filepath.Join(dir, walFile))
+	assert.Equal(t, int64(0), st.Size())
+	assert.NoError(t, ds.Close())
+
+	// key which triggered checkpoint is in snapshot
+	ds, err = OpenDurable(dir, &WALOptions{CheckpointOps: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, ds.Len())
+	assert.Equal(t, true, ds.Has([]byte("4")))
+	for i := 0; i < 5; i++ {
+		_, err = ds.Delete([]byte(fmt.Sprint(i)))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, ds.Close())
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ds.Len())
+	assert.NoError(t, ds.Close())
+}
+
+func TestDurableSetGarbageTail(t *testing.T) {
+	dir := t.TempDir()
+	ds, err := OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	ds.Set([]byte("a"))
+	assert.NoError(t, ds.Close())
+
+	// huge length in garbage tail is a torn record
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	f.Write([]byte{1, 2, 3, 4, opSet, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f})
+	f.Close()
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ds.Len())
+	assert.NoError(t, ds.Close())
+}
+
+func TestDurableSetWriteError(t *testing.T) {
+	dir := t.TempDir()
+	ds, err := OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	ds.Set([]byte("a"))
+	// torn write is cut back, so following records are replayed
+	off, _ := ds.log.Seek(0, io.SeekCurrent)
+	rec := appendRecord(nil, opSet, []byte("torn"))
+	ds.log.Write(rec[:len(rec)-2])
+	assert.NoError(t, ds.rollback(off))
+	ds.Set([]byte("c"))
+	assert.NoError(t, ds.Close())
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ds.Has([]byte("c")))
+	assert.Equal(t, false, ds.Has([]byte("torn")))
+
+	// log which can't be written or cut back fail the set
+	ds.log.Close()
+	_, err = ds.Set([]byte("b"))
+	assert.Error(t, err)
+	assert.Equal(t, false, ds.Has([]byte("b")))
+	_, err = ds.Delete([]byte("a"))
+	assert.Error(t, err)
+	assert.Equal(t, true, ds.Has([]byte("a")))
+
+	ds, err = OpenDurable(dir, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ds.Len())
+	assert.Equal(t, true, ds.Has([]byte("a")))
+	assert.NoError(t, ds.Close())
+}
+
+func TestDurableSetReadError(t *testing.T) {
+	var log []byte
+	log = appendRecord(log, opSet, []byte("a"))
+	log = appendRecord(log, opSet, []byte("b"))
+	size := int64(len(log)) * 2
+
+	// read error is not a torn tail
+	eio := errors.New("eio")
+	var ds DurableSet
+	r := io.MultiReader(bytes.NewReader(log), iotest.ErrReader(eio))
+	_, err := ds.readLog(bufio.NewReader(r), size)
+	assert.Equal(t, eio, err)
+
+	// torn and corrupted tails are
+	for _, tail := range [][]byte{log[:3], {1, 2, 3, 4, opSet, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, {1, 2, 3, 4, opSet, 1, 'x'}} {
+		ds = DurableSet{}
+		offset, err := ds.readLog(bufio.NewReader(bytes.NewReader(append(log[:len(log):len(log)], tail...))), size)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(log)), offset)
+		assert.Equal(t, 2, ds.Len())
+	}
+}
@@ -0,0 +1,97 @@
+package btreeset
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Op is a type of change
+type Op int
+
+const (
+	// OpSet key was set
+	OpSet Op = iota + 1
+	// OpDelete key was deleted
+	OpDelete
+)
+
+// Event describe change of key
+type Event struct {
+	Op  Op
+	Key []byte
+	// Replaced is true if set key existed before
+	Replaced bool
+}
+
+// Watcher is a subscription for changes under prefix
+type Watcher struct {
+	tr     *BTreeSet
+	prefix []byte
+	fn     func(ev Event)
+	ch     chan Event
+	mu     sync.Locker // lock of owner, if any
+}
+
+// Watch call fn on every change of key with prefix, nil prefix match all keys
+// fn is called synchronously from Set, Delete and Batch commit
+// and must not modify the tree
+func (tr *BTreeSet) Watch(prefix []byte, fn func(ev Event)) *Watcher {
+	w := &Watcher{tr: tr, prefix: prefix, fn: fn}
+	tr.watchers = append(tr.watchers, w)
+	return w
+}
+
+// WatchChan deliver changes of keys with prefix to channel with buffer size
+// Set and Delete block when buffer is full, channel is closed on Stop
+func (tr *BTreeSet) WatchChan(prefix []byte, size int) (<-chan Event, *Watcher) {
+	ch := make(chan Event, size)
+	w := tr.Watch(prefix, func(ev Event) {
+		ch <- ev
+	})
+	w.ch = ch
+	return ch, w
+}
+
+// Stop unsubscribe watcher
+func (w *Watcher) Stop() {
+	if w.mu != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+	if w.tr == nil {
+		return
+	}
+	ws := w.tr.watchers
+	for i := range ws {
+		if ws[i] == w {
+			// copy, so notify in progress iterate old slice
+			w.tr.watchers = append(ws[:i:i], ws[i+1:]...)
+			break
+		}
+	}
+	if len(w.tr.watchers) == 0 {
+		w.tr.watchers = nil
+	}
+	if w.ch != nil {
+		close(w.ch)
+	}
+	w.tr = nil
+}
+
+func (tr *BTreeSet) notify(ev Event) {
+	for _, w := range tr.watchers {
+		if w.tr != nil && bytes.HasPrefix(ev.Key, w.prefix) {
+			w.fn(ev)
+		}
+	}
+}
+
+// Watch call fn on every change of key with prefix in latest version
+// fn is called with set locked and must not use the set or stop watcher
+func (vs *VersionedSet) Watch(prefix []byte, fn func(ev Event)) *Watcher {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	w := vs.tr.Watch(prefix, fn)
+	w.mu = &vs.mu
+	return w
+}
@@ -0,0 +1,66 @@
+package btreeset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	var tr BTreeSet
+	var events []Event
+	w := tr.Watch([]byte("user:"), func(ev Event) {
+		events = append(events, ev)
+	})
+	ch, wc := tr.WatchChan(nil, 10)
+
+	tr.Set([]byte("user:1"))
+	tr.Set([]byte("user:1"))
+	tr.Set([]byte("item:1"))
+	tr.Delete([]byte("user:1"))
+	tr.Delete([]byte("user:2"))
+	b := tr.Batch()
+	b.Set([]byte("user:3"))
+	b.Commit()
+
+	assert.Equal(t, []Event{
+		{Op: OpSet, Key: []byte("user:1")},
+		{Op: OpSet, Key: []byte("user:1"), Replaced: true},
+		{Op: OpDelete, Key: []byte("user:1")},
+		{Op: OpSet, Key: []byte("user:3")},
+	}, events)
+	assert.Equal(t, 5, len(ch))
+
+	// copies don't notify
+	cp := tr.Copy()
+	cp.Set([]byte("user:4"))
+	assert.Equal(t, 4, len(events))
+
+	w.Stop()
+	w.Stop()
+	wc.Stop()
+	tr.Set([]byte("user:5"))
+	assert.Equal(t, 4, len(events))
+	var n int
+	for range ch {
+		n++
+	}
+	assert.Equal(t, 5, n)
+	assert.Nil(t, tr.watchers)
+}
+
+func TestVersionedSetWatch(t *testing.T) {
+	var vs VersionedSet
+	var events []Event
+	w := vs.Watch(nil, func(ev Event) {
+		events = append(events, ev)
+	})
+	b := vs.Batch()
+	b.Set([]byte("a"))
+	b.Set([]byte("b"))
+	b.Commit()
+	vs.Delete([]byte("a"))
+	w.Stop()
+	vs.Set([]byte("c"))
+	assert.Equal(t, 3, len(events))
+}
@@ -0,0 +1,146 @@
+package btreeset
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// ZSet is a sorted set of members ordered by score, like redis ZSET
+type ZSet struct {
+	tree   BTreeSet
+	scores map[string]float64
+}
+
+// scoreToBinary encode score, so bytes order match float order
+func scoreToBinary(score float64) []byte {
+	if score == 0 {
+		// -0 == 0, so both must sort as 0
+		score = 0
+	}
+	bits := math.Float64bits(score)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	p := make([]byte, 8)
+	binary.BigEndian.PutUint64(p, bits)
+	return p
+}
+
+func binaryToScore(p []byte) float64 {
+	bits := binary.BigEndian.Uint64(p)
+	if bits&(1<<63) != 0 {
+		bits &^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits)
+}
+
+func zkey(score float64, member []byte) []byte {
+	return append(scoreToBinary(score), member...)
+}
+
+// ZAdd add member or update its score, return true if member is new
+func (z *ZSet) ZAdd(score float64, member []byte) (added bool) {
+	if z.scores == nil {
+		z.scores = make(map[string]float64)
+	}
+	old, ok := z.scores[string(member)]
+	if ok {
+		if old == score {
+			return false
+		}
+		z.tree.Delete(zkey(old, member))
+	}
+	z.scores[string(member)] = score
+	z.tree.Set(zkey(score, member))
+	return !ok
+}
+
+// ZIncrBy increment score of member, return new score
+func (z *ZSet) ZIncrBy(incr float64, member []byte) float64 {
+	score, _ := z.ZScore(member)
+	score += incr
+	z.ZAdd(score, member)
+	return score
+}
+
+// ZScore return score of member
+func (z *ZSet) ZScore(member []byte) (score float64, ok bool) {
+	score, ok = z.scores[string(member)]
+	return
+}
+
+// ZRem remove member
+func (z *ZSet) ZRem(member []byte) (removed bool) {
+	score, ok := z.scores[string(member)]
+	if !ok {
+		return false
+	}
+	delete(z.scores, string(member))
+	return z.tree.Delete(zkey(score, member))
+}
+
+// ZCard return number of members
+func (z *ZSet) ZCard() int {
+	return z.tree.Len()
+}
+
+// ZRank return 0 based rank of member, ordered by score from low to high
+func (z *ZSet) ZRank(member []byte) (rank int, ok bool) {
+	score, ok := z.scores[string(member)]
+	if !ok {
+		return 0, false
+	}
+	key := zkey(score, member)
+	z.tree.Scan(func(k []byte) bool {
+		if string(k) == string(key) {
+			return false
+		}
+		rank++
+		return true
+	})
+	return rank, true
+}
+
+// ZRangeByScore iterate members with score within the range [min, max]
+func (z *ZSet) ZRangeByScore(min, max float64, iter func(member []byte, score float64) bool) {
+	z.tree.Ascend(scoreToBinary(min), func(key []byte) bool {
+		score := binaryToScore(key)
+		if score > max {
+			return false
+		}
+		return iter(key[8:], score)
+	})
+}
+
+// ZRevRange iterate members ordered by score from high to low
+// within ranks [start, stop], negative ranks counted from the end
+func (z *ZSet) ZRevRange(start, stop int, iter func(member []byte, score float64) bool) {
+	n := z.tree.Len()
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= n {
+		return
+	}
+	rank := 0
+	z.tree.Reverse(func(key []byte) bool {
+		if rank > stop {
+			return false
+		}
+		rank++
+		if rank-1 < start {
+			return true
+		}
+		return iter(key[8:], binaryToScore(key))
+	})
+}
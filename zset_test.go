@@ -0,0 +1,85 @@
+package btreeset
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreToBinary(t *testing.T) {
+	scores := []float64{math.Inf(-1), -1e10, -2.5, -0.5, 0, 0.5, 1, 2.5, 1e10, math.Inf(1)}
+	for i := 0; i < 100; i++ {
+		scores = append(scores, rand.NormFloat64()*1000)
+	}
+	sort.Float64s(scores)
+	for i, s := range scores {
+		assert.Equal(t, s, binaryToScore(scoreToBinary(s)))
+		if i > 0 && scores[i-1] < s {
+			assert.Equal(t, true, string(scoreToBinary(scores[i-1])) < string(scoreToBinary(s)))
+		}
+	}
+}
+
+func TestZSet(t *testing.T) {
+	var z ZSet
+	assert.Equal(t, true, z.ZAdd(3, []byte("c")))
+	assert.Equal(t, true, z.ZAdd(-1, []byte("a")))
+	assert.Equal(t, true, z.ZAdd(2, []byte("b")))
+	assert.Equal(t, false, z.ZAdd(2, []byte("b")))
+	assert.Equal(t, 3, z.ZCard())
+
+	rank, ok := z.ZRank([]byte("b"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 1, rank)
+
+	// move a to the end
+	assert.Equal(t, 4.0, z.ZIncrBy(5, []byte("a")))
+	score, ok := z.ZScore([]byte("a"))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 4.0, score)
+	rank, _ = z.ZRank([]byte("a"))
+	assert.Equal(t, 2, rank)
+	assert.Equal(t, 3, z.ZCard())
+
+	var members []string
+	z.ZRangeByScore(2, 3, func(member []byte, score float64) bool {
+		members = append(members, string(member))
+		return true
+	})
+	assert.Equal(t, []string{"b", "c"}, members)
+
+	members = nil
+	z.ZRevRange(0, -1, func(member []byte, score float64) bool {
+		members = append(members, string(member))
+		return true
+	})
+	assert.Equal(t, []string{"a", "c", "b"}, members)
+
+	members = nil
+	z.ZRevRange(1, 1, func(member []byte, score float64) bool {
+		members = append(members, string(member))
+		return true
+	})
+	assert.Equal(t, []string{"c"}, members)
+
+	assert.Equal(t, true, z.ZRem([]byte("c")))
+	assert.Equal(t, false, z.ZRem([]byte("c")))
+	_, ok = z.ZScore([]byte("c"))
+	assert.Equal(t, false, ok)
+	assert.Equal(t, 2, z.ZCard())
+}
+
+func TestZSetNegativeZero(t *testing.T) {
+	var z ZSet
+	z.ZAdd(math.Copysign(0, -1), []byte("neg"))
+	z.ZAdd(0, []byte("pos"))
+	var members []string
+	z.ZRangeByScore(0, 0, func(member []byte, score float64) bool {
+		members = append(members, string(member))
+		return true
+	})
+	assert.Equal(t, []string{"neg", "pos"}, members)
+}